@@ -24,16 +24,54 @@ limitations under the License.
 Package api is a generated protocol buffer package.
 
 It is generated from these files:
+
 	vmregistry.proto
 
 It has these top-level messages:
+
 	VM
+	ListFilter
 	ListVMRequest
 	ListVMReply
+	GetVMRequest
 	FindRequest
 	CreateRequest
+	Flavor
+	ListFlavorsRequest
+	ListFlavorsReply
+	DomainTemplate
+	ListTemplatesRequest
+	ListTemplatesReply
 	DestroyRequest
-	DestroyReply
+	ResizeDiskRequest
+	ResizeRequest
+	ResizeReply
+	Disk
+	AttachDiskRequest
+	DetachDiskRequest
+	ListDisksRequest
+	ListDisksReply
+	Snapshot
+	CreateSnapshotRequest
+	RevertSnapshotRequest
+	DeleteSnapshotRequest
+	ListSnapshotsRequest
+	ListSnapshotsReply
+	Operation
+	GetOperationRequest
+	ListOperationsRequest
+	ListOperationsReply
+	WaitOperationRequest
+	CancelOperationRequest
+	StartRequest
+	StopRequest
+	RebootRequest
+	ResetRequest
+	SuspendRequest
+	ResumeRequest
+	UpdateLabelsRequest
+	WatchRequest
+	VMEvent
 */
 package api
 
@@ -57,6 +95,45 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type VM_State int32
+
+const (
+	VM_NOSTATE     VM_State = 0
+	VM_RUNNING     VM_State = 1
+	VM_BLOCKED     VM_State = 2
+	VM_PAUSED      VM_State = 3
+	VM_SHUTDOWN    VM_State = 4
+	VM_SHUTOFF     VM_State = 5
+	VM_CRASHED     VM_State = 6
+	VM_PMSUSPENDED VM_State = 7
+)
+
+var VM_State_name = map[int32]string{
+	0: "NOSTATE",
+	1: "RUNNING",
+	2: "BLOCKED",
+	3: "PAUSED",
+	4: "SHUTDOWN",
+	5: "SHUTOFF",
+	6: "CRASHED",
+	7: "PMSUSPENDED",
+}
+var VM_State_value = map[string]int32{
+	"NOSTATE":     0,
+	"RUNNING":     1,
+	"BLOCKED":     2,
+	"PAUSED":      3,
+	"SHUTDOWN":    4,
+	"SHUTOFF":     5,
+	"CRASHED":     6,
+	"PMSUSPENDED": 7,
+}
+
+func (x VM_State) String() string {
+	return proto.EnumName(VM_State_name, int32(x))
+}
+func (VM_State) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
 type FindRequest_FindBy int32
 
 const (
@@ -79,12 +156,117 @@ var FindRequest_FindBy_value = map[string]int32{
 func (x FindRequest_FindBy) String() string {
 	return proto.EnumName(FindRequest_FindBy_name, int32(x))
 }
-func (FindRequest_FindBy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3, 0} }
+func (FindRequest_FindBy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 0} }
+
+type Operation_Kind int32
+
+const (
+	Operation_UNKNOWN Operation_Kind = 0
+	Operation_CREATE  Operation_Kind = 1
+	Operation_DESTROY Operation_Kind = 2
+)
+
+var Operation_Kind_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "CREATE",
+	2: "DESTROY",
+}
+var Operation_Kind_value = map[string]int32{
+	"UNKNOWN": 0,
+	"CREATE":  1,
+	"DESTROY": 2,
+}
+
+func (x Operation_Kind) String() string {
+	return proto.EnumName(Operation_Kind_name, int32(x))
+}
+func (Operation_Kind) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{28, 0} }
+
+type Operation_Status int32
+
+const (
+	Operation_PENDING   Operation_Status = 0
+	Operation_RUNNING   Operation_Status = 1
+	Operation_DONE      Operation_Status = 2
+	Operation_FAILED    Operation_Status = 3
+	Operation_CANCELLED Operation_Status = 4
+)
+
+var Operation_Status_name = map[int32]string{
+	0: "PENDING",
+	1: "RUNNING",
+	2: "DONE",
+	3: "FAILED",
+	4: "CANCELLED",
+}
+var Operation_Status_value = map[string]int32{
+	"PENDING":   0,
+	"RUNNING":   1,
+	"DONE":      2,
+	"FAILED":    3,
+	"CANCELLED": 4,
+}
+
+func (x Operation_Status) String() string {
+	return proto.EnumName(Operation_Status_name, int32(x))
+}
+func (Operation_Status) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{28, 1} }
+
+type VMEvent_Type int32
+
+const (
+	VMEvent_UNKNOWN   VMEvent_Type = 0
+	VMEvent_CREATED   VMEvent_Type = 1
+	VMEvent_STARTED   VMEvent_Type = 2
+	VMEvent_STOPPED   VMEvent_Type = 3
+	VMEvent_DESTROYED VMEvent_Type = 4
+	VMEvent_CRASHED   VMEvent_Type = 5
+	VMEvent_SUSPENDED VMEvent_Type = 6
+	VMEvent_RESUMED   VMEvent_Type = 7
+)
+
+var VMEvent_Type_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "CREATED",
+	2: "STARTED",
+	3: "STOPPED",
+	4: "DESTROYED",
+	5: "CRASHED",
+	6: "SUSPENDED",
+	7: "RESUMED",
+}
+var VMEvent_Type_value = map[string]int32{
+	"UNKNOWN":   0,
+	"CREATED":   1,
+	"STARTED":   2,
+	"STOPPED":   3,
+	"DESTROYED": 4,
+	"CRASHED":   5,
+	"SUSPENDED": 6,
+	"RESUMED":   7,
+}
+
+func (x VMEvent_Type) String() string {
+	return proto.EnumName(VMEvent_Type_name, int32(x))
+}
+func (VMEvent_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{42, 0} }
 
 type VM struct {
-	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
-	Mac  string `protobuf:"bytes,2,opt,name=mac" json:"mac,omitempty"`
-	Ip   string `protobuf:"bytes,3,opt,name=ip" json:"ip,omitempty"`
+	Name        string            `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Mac         string            `protobuf:"bytes,2,opt,name=mac" json:"mac,omitempty"`
+	Ip          string            `protobuf:"bytes,3,opt,name=ip" json:"ip,omitempty"`
+	Mem         uint64            `protobuf:"varint,4,opt,name=mem" json:"mem,omitempty"`
+	Cores       uint32            `protobuf:"varint,5,opt,name=cores" json:"cores,omitempty"`
+	Size        uint64            `protobuf:"varint,6,opt,name=size" json:"size,omitempty"`
+	SourceImage string            `protobuf:"bytes,7,opt,name=source_image,json=sourceImage" json:"source_image,omitempty"`
+	State       VM_State          `protobuf:"varint,8,opt,name=state,enum=api.VM_State" json:"state,omitempty"`
+	Macs        []string          `protobuf:"bytes,9,rep,name=macs" json:"macs,omitempty"`
+	Created     int64             `protobuf:"varint,10,opt,name=created" json:"created,omitempty"`
+	Network     string            `protobuf:"bytes,11,opt,name=network" json:"network,omitempty"`
+	Ipv6        string            `protobuf:"bytes,12,opt,name=ipv6" json:"ipv6,omitempty"`
+	Labels      map[string]string `protobuf:"bytes,13,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Flavor      string            `protobuf:"bytes,14,opt,name=flavor" json:"flavor,omitempty"`
+	Template    string            `protobuf:"bytes,15,opt,name=template" json:"template,omitempty"`
 }
 
 func (m *VM) Reset()                    { *m = VM{} }
@@ -113,22 +295,193 @@ func (m *VM) GetIp() string {
 	return ""
 }
 
+func (m *VM) GetMem() uint64 {
+	if m != nil {
+		return m.Mem
+	}
+	return 0
+}
+
+func (m *VM) GetCores() uint32 {
+	if m != nil {
+		return m.Cores
+	}
+	return 0
+}
+
+func (m *VM) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *VM) GetSourceImage() string {
+	if m != nil {
+		return m.SourceImage
+	}
+	return ""
+}
+
+func (m *VM) GetState() VM_State {
+	if m != nil {
+		return m.State
+	}
+	return VM_NOSTATE
+}
+
+func (m *VM) GetMacs() []string {
+	if m != nil {
+		return m.Macs
+	}
+	return nil
+}
+
+func (m *VM) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *VM) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *VM) GetIpv6() string {
+	if m != nil {
+		return m.Ipv6
+	}
+	return ""
+}
+
+func (m *VM) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *VM) GetFlavor() string {
+	if m != nil {
+		return m.Flavor
+	}
+	return ""
+}
+
+func (m *VM) GetTemplate() string {
+	if m != nil {
+		return m.Template
+	}
+	return ""
+}
+
+type ListFilter struct {
+	Name    string     `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	States  []VM_State `protobuf:"varint,2,rep,packed,name=states,enum=api.VM_State" json:"states,omitempty"`
+	Network string     `protobuf:"bytes,3,opt,name=network" json:"network,omitempty"`
+	// comma-separated requirements, all of which must match: key=value,
+	// key!=value, key (label set) or !key (label not set).
+	LabelSelector string `protobuf:"bytes,4,opt,name=label_selector,json=labelSelector" json:"label_selector,omitempty"`
+}
+
+func (m *ListFilter) Reset()                    { *m = ListFilter{} }
+func (m *ListFilter) String() string            { return proto.CompactTextString(m) }
+func (*ListFilter) ProtoMessage()               {}
+func (*ListFilter) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *ListFilter) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ListFilter) GetStates() []VM_State {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+func (m *ListFilter) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *ListFilter) GetLabelSelector() string {
+	if m != nil {
+		return m.LabelSelector
+	}
+	return ""
+}
+
 type ListVMRequest struct {
+	// deprecated, use filter.label_selector. Still honoured, VMs must match
+	// both selectors if both are set.
+	LabelSelector string      `protobuf:"bytes,1,opt,name=label_selector,json=labelSelector" json:"label_selector,omitempty"`
+	Filter        *ListFilter `protobuf:"bytes,2,opt,name=filter" json:"filter,omitempty"`
+	// name (default), created, mem, cores, size, state, network or ip,
+	// followed by "desc" for descending order.
+	OrderBy   string `protobuf:"bytes,3,opt,name=order_by,json=orderBy" json:"order_by,omitempty"`
+	PageSize  uint32 `protobuf:"varint,4,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	PageToken string `protobuf:"bytes,5,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
 }
 
 func (m *ListVMRequest) Reset()                    { *m = ListVMRequest{} }
 func (m *ListVMRequest) String() string            { return proto.CompactTextString(m) }
 func (*ListVMRequest) ProtoMessage()               {}
-func (*ListVMRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*ListVMRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *ListVMRequest) GetLabelSelector() string {
+	if m != nil {
+		return m.LabelSelector
+	}
+	return ""
+}
+
+func (m *ListVMRequest) GetFilter() *ListFilter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+func (m *ListVMRequest) GetOrderBy() string {
+	if m != nil {
+		return m.OrderBy
+	}
+	return ""
+}
+
+func (m *ListVMRequest) GetPageSize() uint32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListVMRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
 
 type ListVMReply struct {
-	Vms []*VM `protobuf:"bytes,1,rep,name=vms" json:"vms,omitempty"`
+	Vms           []*VM  `protobuf:"bytes,1,rep,name=vms" json:"vms,omitempty"`
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
 }
 
 func (m *ListVMReply) Reset()                    { *m = ListVMReply{} }
 func (m *ListVMReply) String() string            { return proto.CompactTextString(m) }
 func (*ListVMReply) ProtoMessage()               {}
-func (*ListVMReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*ListVMReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *ListVMReply) GetVms() []*VM {
 	if m != nil {
@@ -137,6 +490,29 @@ func (m *ListVMReply) GetVms() []*VM {
 	return nil
 }
 
+func (m *ListVMReply) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type GetVMRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *GetVMRequest) Reset()                    { *m = GetVMRequest{} }
+func (m *GetVMRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetVMRequest) ProtoMessage()               {}
+func (*GetVMRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *GetVMRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
 type FindRequest struct {
 	FindBy FindRequest_FindBy `protobuf:"varint,1,opt,name=find_by,json=findBy,enum=api.FindRequest_FindBy" json:"find_by,omitempty"`
 	Value  string             `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
@@ -145,7 +521,7 @@ type FindRequest struct {
 func (m *FindRequest) Reset()                    { *m = FindRequest{} }
 func (m *FindRequest) String() string            { return proto.CompactTextString(m) }
 func (*FindRequest) ProtoMessage()               {}
-func (*FindRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*FindRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *FindRequest) GetFindBy() FindRequest_FindBy {
 	if m != nil {
@@ -167,12 +543,27 @@ type CreateRequest struct {
 	Cores       uint32 `protobuf:"varint,3,opt,name=cores" json:"cores,omitempty"`
 	Size        uint64 `protobuf:"varint,4,opt,name=size" json:"size,omitempty"`
 	SourceImage string `protobuf:"bytes,5,opt,name=source_image,json=sourceImage" json:"source_image,omitempty"`
+	Network     string `protobuf:"bytes,6,opt,name=network" json:"network,omitempty"`
+	// retries with the same id return the original operation. The id is kept
+	// in the VM metadata, so retries after the operation is forgotten get a
+	// finished operation for the VM.
+	RequestId string            `protobuf:"bytes,7,opt,name=request_id,json=requestId" json:"request_id,omitempty"`
+	Labels    map[string]string `protobuf:"bytes,8,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// named server-side sizing, mem, cores and size set in the request take
+	// precedence over the flavor ones.
+	Flavor string `protobuf:"bytes,9,opt,name=flavor" json:"flavor,omitempty"`
+	// domain template, defaults to the flavor one, then to the server default.
+	Template string `protobuf:"bytes,10,opt,name=template" json:"template,omitempty"`
+	// cloud-init NoCloud seed, attached to the VM when any of these is set.
+	SshAuthorizedKeys []string `protobuf:"bytes,11,rep,name=ssh_authorized_keys,json=sshAuthorizedKeys" json:"ssh_authorized_keys,omitempty"`
+	UserData          string   `protobuf:"bytes,12,opt,name=user_data,json=userData" json:"user_data,omitempty"`
+	Hostname          string   `protobuf:"bytes,13,opt,name=hostname" json:"hostname,omitempty"`
 }
 
 func (m *CreateRequest) Reset()                    { *m = CreateRequest{} }
 func (m *CreateRequest) String() string            { return proto.CompactTextString(m) }
 func (*CreateRequest) ProtoMessage()               {}
-func (*CreateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*CreateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *CreateRequest) GetName() string {
 	if m != nil {
@@ -209,187 +600,1951 @@ func (m *CreateRequest) GetSourceImage() string {
 	return ""
 }
 
-type DestroyRequest struct {
-	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+func (m *CreateRequest) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
 }
 
-func (m *DestroyRequest) Reset()                    { *m = DestroyRequest{} }
-func (m *DestroyRequest) String() string            { return proto.CompactTextString(m) }
-func (*DestroyRequest) ProtoMessage()               {}
-func (*DestroyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
-
-func (m *DestroyRequest) GetName() string {
+func (m *CreateRequest) GetRequestId() string {
 	if m != nil {
-		return m.Name
+		return m.RequestId
 	}
 	return ""
 }
 
-type DestroyReply struct {
+func (m *CreateRequest) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
 }
 
-func (m *DestroyReply) Reset()                    { *m = DestroyReply{} }
-func (m *DestroyReply) String() string            { return proto.CompactTextString(m) }
-func (*DestroyReply) ProtoMessage()               {}
-func (*DestroyReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
-
-func init() {
-	proto.RegisterType((*VM)(nil), "api.VM")
-	proto.RegisterType((*ListVMRequest)(nil), "api.ListVMRequest")
-	proto.RegisterType((*ListVMReply)(nil), "api.ListVMReply")
-	proto.RegisterType((*FindRequest)(nil), "api.FindRequest")
-	proto.RegisterType((*CreateRequest)(nil), "api.CreateRequest")
-	proto.RegisterType((*DestroyRequest)(nil), "api.DestroyRequest")
-	proto.RegisterType((*DestroyReply)(nil), "api.DestroyReply")
-	proto.RegisterEnum("api.FindRequest_FindBy", FindRequest_FindBy_name, FindRequest_FindBy_value)
+func (m *CreateRequest) GetFlavor() string {
+	if m != nil {
+		return m.Flavor
+	}
+	return ""
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *CreateRequest) GetTemplate() string {
+	if m != nil {
+		return m.Template
+	}
+	return ""
+}
 
-// Client API for VMRegistry service
+func (m *CreateRequest) GetSshAuthorizedKeys() []string {
+	if m != nil {
+		return m.SshAuthorizedKeys
+	}
+	return nil
+}
 
-type VMRegistryClient interface {
-	List(ctx context.Context, in *ListVMRequest, opts ...grpc.CallOption) (*ListVMReply, error)
-	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*VM, error)
-	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*VM, error)
-	Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*DestroyReply, error)
+func (m *CreateRequest) GetUserData() string {
+	if m != nil {
+		return m.UserData
+	}
+	return ""
 }
 
-type vMRegistryClient struct {
-	cc *grpc.ClientConn
+func (m *CreateRequest) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
 }
 
-func NewVMRegistryClient(cc *grpc.ClientConn) VMRegistryClient {
-	return &vMRegistryClient{cc}
+type Flavor struct {
+	Name     string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Mem      uint64 `protobuf:"varint,2,opt,name=mem" json:"mem,omitempty"`
+	Cores    uint32 `protobuf:"varint,3,opt,name=cores" json:"cores,omitempty"`
+	Size     uint64 `protobuf:"varint,4,opt,name=size" json:"size,omitempty"`
+	Template string `protobuf:"bytes,5,opt,name=template" json:"template,omitempty"`
 }
 
-func (c *vMRegistryClient) List(ctx context.Context, in *ListVMRequest, opts ...grpc.CallOption) (*ListVMReply, error) {
-	out := new(ListVMReply)
-	err := grpc.Invoke(ctx, "/api.VMRegistry/List", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *Flavor) Reset()                    { *m = Flavor{} }
+func (m *Flavor) String() string            { return proto.CompactTextString(m) }
+func (*Flavor) ProtoMessage()               {}
+func (*Flavor) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *Flavor) GetName() string {
+	if m != nil {
+		return m.Name
 	}
-	return out, nil
+	return ""
 }
 
-func (c *vMRegistryClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*VM, error) {
-	out := new(VM)
-	err := grpc.Invoke(ctx, "/api.VMRegistry/Find", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *Flavor) GetMem() uint64 {
+	if m != nil {
+		return m.Mem
 	}
-	return out, nil
+	return 0
 }
 
-func (c *vMRegistryClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*VM, error) {
-	out := new(VM)
-	err := grpc.Invoke(ctx, "/api.VMRegistry/Create", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *Flavor) GetCores() uint32 {
+	if m != nil {
+		return m.Cores
 	}
-	return out, nil
+	return 0
 }
 
-func (c *vMRegistryClient) Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*DestroyReply, error) {
-	out := new(DestroyReply)
-	err := grpc.Invoke(ctx, "/api.VMRegistry/Destroy", in, out, c.cc, opts...)
-	if err != nil {
-		return nil, err
+func (m *Flavor) GetSize() uint64 {
+	if m != nil {
+		return m.Size
 	}
-	return out, nil
+	return 0
 }
 
-// Server API for VMRegistry service
+func (m *Flavor) GetTemplate() string {
+	if m != nil {
+		return m.Template
+	}
+	return ""
+}
+
+type ListFlavorsRequest struct {
+}
+
+func (m *ListFlavorsRequest) Reset()                    { *m = ListFlavorsRequest{} }
+func (m *ListFlavorsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListFlavorsRequest) ProtoMessage()               {}
+func (*ListFlavorsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type ListFlavorsReply struct {
+	Flavors []*Flavor `protobuf:"bytes,1,rep,name=flavors" json:"flavors,omitempty"`
+}
+
+func (m *ListFlavorsReply) Reset()                    { *m = ListFlavorsReply{} }
+func (m *ListFlavorsReply) String() string            { return proto.CompactTextString(m) }
+func (*ListFlavorsReply) ProtoMessage()               {}
+func (*ListFlavorsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *ListFlavorsReply) GetFlavors() []*Flavor {
+	if m != nil {
+		return m.Flavors
+	}
+	return nil
+}
+
+type DomainTemplate struct {
+	Name      string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	IsDefault bool   `protobuf:"varint,2,opt,name=is_default,json=isDefault" json:"is_default,omitempty"`
+}
+
+func (m *DomainTemplate) Reset()                    { *m = DomainTemplate{} }
+func (m *DomainTemplate) String() string            { return proto.CompactTextString(m) }
+func (*DomainTemplate) ProtoMessage()               {}
+func (*DomainTemplate) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *DomainTemplate) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DomainTemplate) GetIsDefault() bool {
+	if m != nil {
+		return m.IsDefault
+	}
+	return false
+}
+
+type ListTemplatesRequest struct {
+}
+
+func (m *ListTemplatesRequest) Reset()                    { *m = ListTemplatesRequest{} }
+func (m *ListTemplatesRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListTemplatesRequest) ProtoMessage()               {}
+func (*ListTemplatesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type ListTemplatesReply struct {
+	Templates []*DomainTemplate `protobuf:"bytes,1,rep,name=templates" json:"templates,omitempty"`
+}
+
+func (m *ListTemplatesReply) Reset()                    { *m = ListTemplatesReply{} }
+func (m *ListTemplatesReply) String() string            { return proto.CompactTextString(m) }
+func (*ListTemplatesReply) ProtoMessage()               {}
+func (*ListTemplatesReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *ListTemplatesReply) GetTemplates() []*DomainTemplate {
+	if m != nil {
+		return m.Templates
+	}
+	return nil
+}
+
+type DestroyRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// retries with the same id return the original operation. Once it's
+	// forgotten, destroying the missing VM succeeds anyway.
+	RequestId string `protobuf:"bytes,2,opt,name=request_id,json=requestId" json:"request_id,omitempty"`
+}
+
+func (m *DestroyRequest) Reset()                    { *m = DestroyRequest{} }
+func (m *DestroyRequest) String() string            { return proto.CompactTextString(m) }
+func (*DestroyRequest) ProtoMessage()               {}
+func (*DestroyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *DestroyRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DestroyRequest) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+type ResizeDiskRequest struct {
+	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Size  uint64 `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+	Force bool   `protobuf:"varint,3,opt,name=force" json:"force,omitempty"`
+}
+
+func (m *ResizeDiskRequest) Reset()                    { *m = ResizeDiskRequest{} }
+func (m *ResizeDiskRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResizeDiskRequest) ProtoMessage()               {}
+func (*ResizeDiskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *ResizeDiskRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ResizeDiskRequest) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *ResizeDiskRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+type ResizeRequest struct {
+	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Mem   uint64 `protobuf:"varint,2,opt,name=mem" json:"mem,omitempty"`
+	Cores uint32 `protobuf:"varint,3,opt,name=cores" json:"cores,omitempty"`
+}
+
+func (m *ResizeRequest) Reset()                    { *m = ResizeRequest{} }
+func (m *ResizeRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResizeRequest) ProtoMessage()               {}
+func (*ResizeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ResizeRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ResizeRequest) GetMem() uint64 {
+	if m != nil {
+		return m.Mem
+	}
+	return 0
+}
+
+func (m *ResizeRequest) GetCores() uint32 {
+	if m != nil {
+		return m.Cores
+	}
+	return 0
+}
+
+type ResizeReply struct {
+	Vm *VM `protobuf:"bytes,1,opt,name=vm" json:"vm,omitempty"`
+	// the new sizing is only partly applied to the running VM, the rest takes
+	// effect on the next boot.
+	RebootRequired bool `protobuf:"varint,2,opt,name=reboot_required,json=rebootRequired" json:"reboot_required,omitempty"`
+}
+
+func (m *ResizeReply) Reset()                    { *m = ResizeReply{} }
+func (m *ResizeReply) String() string            { return proto.CompactTextString(m) }
+func (*ResizeReply) ProtoMessage()               {}
+func (*ResizeReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *ResizeReply) GetVm() *VM {
+	if m != nil {
+		return m.Vm
+	}
+	return nil
+}
+
+func (m *ResizeReply) GetRebootRequired() bool {
+	if m != nil {
+		return m.RebootRequired
+	}
+	return false
+}
+
+// Disk is a data volume attached to a VM, on top of its boot disk.
+type Disk struct {
+	Name   string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Size   uint64 `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+	Target string `protobuf:"bytes,3,opt,name=target" json:"target,omitempty"`
+	Retain bool   `protobuf:"varint,4,opt,name=retain" json:"retain,omitempty"`
+	Path   string `protobuf:"bytes,5,opt,name=path" json:"path,omitempty"`
+	// detach requested, the disk is removed once the guest releases it. Retry
+	// DetachDisk to finish.
+	Detaching bool `protobuf:"varint,6,opt,name=detaching" json:"detaching,omitempty"`
+}
+
+func (m *Disk) Reset()                    { *m = Disk{} }
+func (m *Disk) String() string            { return proto.CompactTextString(m) }
+func (*Disk) ProtoMessage()               {}
+func (*Disk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *Disk) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Disk) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Disk) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *Disk) GetRetain() bool {
+	if m != nil {
+		return m.Retain
+	}
+	return false
+}
+
+func (m *Disk) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Disk) GetDetaching() bool {
+	if m != nil {
+		return m.Detaching
+	}
+	return false
+}
+
+type AttachDiskRequest struct {
+	Name   string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Disk   string `protobuf:"bytes,2,opt,name=disk" json:"disk,omitempty"`
+	Size   uint64 `protobuf:"varint,3,opt,name=size" json:"size,omitempty"`
+	Retain bool   `protobuf:"varint,4,opt,name=retain" json:"retain,omitempty"`
+}
+
+func (m *AttachDiskRequest) Reset()                    { *m = AttachDiskRequest{} }
+func (m *AttachDiskRequest) String() string            { return proto.CompactTextString(m) }
+func (*AttachDiskRequest) ProtoMessage()               {}
+func (*AttachDiskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *AttachDiskRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AttachDiskRequest) GetDisk() string {
+	if m != nil {
+		return m.Disk
+	}
+	return ""
+}
+
+func (m *AttachDiskRequest) GetSize() uint64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *AttachDiskRequest) GetRetain() bool {
+	if m != nil {
+		return m.Retain
+	}
+	return false
+}
+
+type DetachDiskRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Disk string `protobuf:"bytes,2,opt,name=disk" json:"disk,omitempty"`
+}
+
+func (m *DetachDiskRequest) Reset()                    { *m = DetachDiskRequest{} }
+func (m *DetachDiskRequest) String() string            { return proto.CompactTextString(m) }
+func (*DetachDiskRequest) ProtoMessage()               {}
+func (*DetachDiskRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *DetachDiskRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DetachDiskRequest) GetDisk() string {
+	if m != nil {
+		return m.Disk
+	}
+	return ""
+}
+
+type ListDisksRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ListDisksRequest) Reset()                    { *m = ListDisksRequest{} }
+func (m *ListDisksRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListDisksRequest) ProtoMessage()               {}
+func (*ListDisksRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *ListDisksRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ListDisksReply struct {
+	Disks []*Disk `protobuf:"bytes,1,rep,name=disks" json:"disks,omitempty"`
+}
+
+func (m *ListDisksReply) Reset()                    { *m = ListDisksReply{} }
+func (m *ListDisksReply) String() string            { return proto.CompactTextString(m) }
+func (*ListDisksReply) ProtoMessage()               {}
+func (*ListDisksReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *ListDisksReply) GetDisks() []*Disk {
+	if m != nil {
+		return m.Disks
+	}
+	return nil
+}
+
+// Snapshot is a point in time copy of the boot and data disks of a VM.
+type Snapshot struct {
+	Name    string   `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Created int64    `protobuf:"varint,2,opt,name=created" json:"created,omitempty"`
+	Disks   []string `protobuf:"bytes,3,rep,name=disks" json:"disks,omitempty"`
+	// guest file systems were frozen through the qemu guest agent, so the
+	// snapshot is consistent as after a clean shutdown. Otherwise it's only
+	// crash consistent: all the disks are taken at the same point of guest
+	// time, as after a power loss.
+	Quiesced bool `protobuf:"varint,4,opt,name=quiesced" json:"quiesced,omitempty"`
+}
+
+func (m *Snapshot) Reset()                    { *m = Snapshot{} }
+func (m *Snapshot) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()               {}
+func (*Snapshot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *Snapshot) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Snapshot) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *Snapshot) GetDisks() []string {
+	if m != nil {
+		return m.Disks
+	}
+	return nil
+}
+
+func (m *Snapshot) GetQuiesced() bool {
+	if m != nil {
+		return m.Quiesced
+	}
+	return false
+}
+
+// CreateSnapshotRequest snapshots the boot and data disks of a VM. A running
+// VM is paused meanwhile, and its file systems are frozen first if the guest
+// runs the qemu guest agent. File storage needs the VM shut off instead.
+type CreateSnapshotRequest struct {
+	Name     string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Snapshot string `protobuf:"bytes,2,opt,name=snapshot" json:"snapshot,omitempty"`
+}
+
+func (m *CreateSnapshotRequest) Reset()                    { *m = CreateSnapshotRequest{} }
+func (m *CreateSnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*CreateSnapshotRequest) ProtoMessage()               {}
+func (*CreateSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *CreateSnapshotRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateSnapshotRequest) GetSnapshot() string {
+	if m != nil {
+		return m.Snapshot
+	}
+	return ""
+}
+
+type RevertSnapshotRequest struct {
+	Name     string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Snapshot string `protobuf:"bytes,2,opt,name=snapshot" json:"snapshot,omitempty"`
+}
+
+func (m *RevertSnapshotRequest) Reset()                    { *m = RevertSnapshotRequest{} }
+func (m *RevertSnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevertSnapshotRequest) ProtoMessage()               {}
+func (*RevertSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *RevertSnapshotRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RevertSnapshotRequest) GetSnapshot() string {
+	if m != nil {
+		return m.Snapshot
+	}
+	return ""
+}
+
+type DeleteSnapshotRequest struct {
+	Name     string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Snapshot string `protobuf:"bytes,2,opt,name=snapshot" json:"snapshot,omitempty"`
+}
+
+func (m *DeleteSnapshotRequest) Reset()                    { *m = DeleteSnapshotRequest{} }
+func (m *DeleteSnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteSnapshotRequest) ProtoMessage()               {}
+func (*DeleteSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *DeleteSnapshotRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DeleteSnapshotRequest) GetSnapshot() string {
+	if m != nil {
+		return m.Snapshot
+	}
+	return ""
+}
+
+type ListSnapshotsRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ListSnapshotsRequest) Reset()                    { *m = ListSnapshotsRequest{} }
+func (m *ListSnapshotsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListSnapshotsRequest) ProtoMessage()               {}
+func (*ListSnapshotsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *ListSnapshotsRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ListSnapshotsReply struct {
+	Snapshots []*Snapshot `protobuf:"bytes,1,rep,name=snapshots" json:"snapshots,omitempty"`
+}
+
+func (m *ListSnapshotsReply) Reset()                    { *m = ListSnapshotsReply{} }
+func (m *ListSnapshotsReply) String() string            { return proto.CompactTextString(m) }
+func (*ListSnapshotsReply) ProtoMessage()               {}
+func (*ListSnapshotsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+func (m *ListSnapshotsReply) GetSnapshots() []*Snapshot {
+	if m != nil {
+		return m.Snapshots
+	}
+	return nil
+}
+
+type Operation struct {
+	Id        string            `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Kind      Operation_Kind    `protobuf:"varint,2,opt,name=kind,enum=api.Operation_Kind" json:"kind,omitempty"`
+	VmName    string            `protobuf:"bytes,3,opt,name=vm_name,json=vmName" json:"vm_name,omitempty"`
+	Status    Operation_Status  `protobuf:"varint,4,opt,name=status,enum=api.Operation_Status" json:"status,omitempty"`
+	Steps     []*Operation_Step `protobuf:"bytes,5,rep,name=steps" json:"steps,omitempty"`
+	Created   int64             `protobuf:"varint,6,opt,name=created" json:"created,omitempty"`
+	Finished  int64             `protobuf:"varint,7,opt,name=finished" json:"finished,omitempty"`
+	Vm        *VM               `protobuf:"bytes,8,opt,name=vm" json:"vm,omitempty"`
+	ErrorCode int32             `protobuf:"varint,9,opt,name=error_code,json=errorCode" json:"error_code,omitempty"`
+	Error     string            `protobuf:"bytes,10,opt,name=error" json:"error,omitempty"`
+	RequestId string            `protobuf:"bytes,11,opt,name=request_id,json=requestId" json:"request_id,omitempty"`
+}
+
+func (m *Operation) Reset()                    { *m = Operation{} }
+func (m *Operation) String() string            { return proto.CompactTextString(m) }
+func (*Operation) ProtoMessage()               {}
+func (*Operation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *Operation) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Operation) GetKind() Operation_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return Operation_UNKNOWN
+}
+
+func (m *Operation) GetVmName() string {
+	if m != nil {
+		return m.VmName
+	}
+	return ""
+}
+
+func (m *Operation) GetStatus() Operation_Status {
+	if m != nil {
+		return m.Status
+	}
+	return Operation_PENDING
+}
+
+func (m *Operation) GetSteps() []*Operation_Step {
+	if m != nil {
+		return m.Steps
+	}
+	return nil
+}
+
+func (m *Operation) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *Operation) GetFinished() int64 {
+	if m != nil {
+		return m.Finished
+	}
+	return 0
+}
+
+func (m *Operation) GetVm() *VM {
+	if m != nil {
+		return m.Vm
+	}
+	return nil
+}
+
+func (m *Operation) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *Operation) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Operation) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+type Operation_Step struct {
+	Name     string           `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Status   Operation_Status `protobuf:"varint,2,opt,name=status,enum=api.Operation_Status" json:"status,omitempty"`
+	Started  int64            `protobuf:"varint,3,opt,name=started" json:"started,omitempty"`
+	Finished int64            `protobuf:"varint,4,opt,name=finished" json:"finished,omitempty"`
+}
+
+func (m *Operation_Step) Reset()                    { *m = Operation_Step{} }
+func (m *Operation_Step) String() string            { return proto.CompactTextString(m) }
+func (*Operation_Step) ProtoMessage()               {}
+func (*Operation_Step) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28, 0} }
+
+func (m *Operation_Step) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Operation_Step) GetStatus() Operation_Status {
+	if m != nil {
+		return m.Status
+	}
+	return Operation_PENDING
+}
+
+func (m *Operation_Step) GetStarted() int64 {
+	if m != nil {
+		return m.Started
+	}
+	return 0
+}
+
+func (m *Operation_Step) GetFinished() int64 {
+	if m != nil {
+		return m.Finished
+	}
+	return 0
+}
+
+type GetOperationRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *GetOperationRequest) Reset()                    { *m = GetOperationRequest{} }
+func (m *GetOperationRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetOperationRequest) ProtoMessage()               {}
+func (*GetOperationRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+func (m *GetOperationRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type ListOperationsRequest struct {
+	VmName string `protobuf:"bytes,1,opt,name=vm_name,json=vmName" json:"vm_name,omitempty"`
+}
+
+func (m *ListOperationsRequest) Reset()                    { *m = ListOperationsRequest{} }
+func (m *ListOperationsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListOperationsRequest) ProtoMessage()               {}
+func (*ListOperationsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *ListOperationsRequest) GetVmName() string {
+	if m != nil {
+		return m.VmName
+	}
+	return ""
+}
+
+type ListOperationsReply struct {
+	Operations []*Operation `protobuf:"bytes,1,rep,name=operations" json:"operations,omitempty"`
+}
+
+func (m *ListOperationsReply) Reset()                    { *m = ListOperationsReply{} }
+func (m *ListOperationsReply) String() string            { return proto.CompactTextString(m) }
+func (*ListOperationsReply) ProtoMessage()               {}
+func (*ListOperationsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *ListOperationsReply) GetOperations() []*Operation {
+	if m != nil {
+		return m.Operations
+	}
+	return nil
+}
+
+type WaitOperationRequest struct {
+	Id      string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Timeout uint32 `protobuf:"varint,2,opt,name=timeout" json:"timeout,omitempty"`
+}
+
+func (m *WaitOperationRequest) Reset()                    { *m = WaitOperationRequest{} }
+func (m *WaitOperationRequest) String() string            { return proto.CompactTextString(m) }
+func (*WaitOperationRequest) ProtoMessage()               {}
+func (*WaitOperationRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *WaitOperationRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *WaitOperationRequest) GetTimeout() uint32 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+// Cancelled creates are rolled back. Destroys can only be cancelled before
+// their first step.
+type CancelOperationRequest struct {
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+}
+
+func (m *CancelOperationRequest) Reset()                    { *m = CancelOperationRequest{} }
+func (m *CancelOperationRequest) String() string            { return proto.CompactTextString(m) }
+func (*CancelOperationRequest) ProtoMessage()               {}
+func (*CancelOperationRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *CancelOperationRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type StartRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *StartRequest) Reset()                    { *m = StartRequest{} }
+func (m *StartRequest) String() string            { return proto.CompactTextString(m) }
+func (*StartRequest) ProtoMessage()               {}
+func (*StartRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *StartRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type StopRequest struct {
+	Name    string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Timeout uint32 `protobuf:"varint,2,opt,name=timeout" json:"timeout,omitempty"`
+	Force   bool   `protobuf:"varint,3,opt,name=force" json:"force,omitempty"`
+}
+
+func (m *StopRequest) Reset()                    { *m = StopRequest{} }
+func (m *StopRequest) String() string            { return proto.CompactTextString(m) }
+func (*StopRequest) ProtoMessage()               {}
+func (*StopRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *StopRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *StopRequest) GetTimeout() uint32 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+func (m *StopRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+type RebootRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *RebootRequest) Reset()                    { *m = RebootRequest{} }
+func (m *RebootRequest) String() string            { return proto.CompactTextString(m) }
+func (*RebootRequest) ProtoMessage()               {}
+func (*RebootRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+func (m *RebootRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ResetRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ResetRequest) Reset()                    { *m = ResetRequest{} }
+func (m *ResetRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResetRequest) ProtoMessage()               {}
+func (*ResetRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+func (m *ResetRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type SuspendRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *SuspendRequest) Reset()                    { *m = SuspendRequest{} }
+func (m *SuspendRequest) String() string            { return proto.CompactTextString(m) }
+func (*SuspendRequest) ProtoMessage()               {}
+func (*SuspendRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{38} }
+
+func (m *SuspendRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ResumeRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ResumeRequest) Reset()                    { *m = ResumeRequest{} }
+func (m *ResumeRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResumeRequest) ProtoMessage()               {}
+func (*ResumeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+func (m *ResumeRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type UpdateLabelsRequest struct {
+	Name   string            `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Set    map[string]string `protobuf:"bytes,2,rep,name=set" json:"set,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Remove []string          `protobuf:"bytes,3,rep,name=remove" json:"remove,omitempty"`
+}
+
+func (m *UpdateLabelsRequest) Reset()                    { *m = UpdateLabelsRequest{} }
+func (m *UpdateLabelsRequest) String() string            { return proto.CompactTextString(m) }
+func (*UpdateLabelsRequest) ProtoMessage()               {}
+func (*UpdateLabelsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{40} }
+
+func (m *UpdateLabelsRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UpdateLabelsRequest) GetSet() map[string]string {
+	if m != nil {
+		return m.Set
+	}
+	return nil
+}
+
+func (m *UpdateLabelsRequest) GetRemove() []string {
+	if m != nil {
+		return m.Remove
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *WatchRequest) Reset()                    { *m = WatchRequest{} }
+func (m *WatchRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()               {}
+func (*WatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
+
+func (m *WatchRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type VMEvent struct {
+	Type VMEvent_Type `protobuf:"varint,1,opt,name=type,enum=api.VMEvent_Type" json:"type,omitempty"`
+	Name string       `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Time int64        `protobuf:"varint,3,opt,name=time" json:"time,omitempty"`
+	Vm   *VM          `protobuf:"bytes,4,opt,name=vm" json:"vm,omitempty"`
+}
+
+func (m *VMEvent) Reset()                    { *m = VMEvent{} }
+func (m *VMEvent) String() string            { return proto.CompactTextString(m) }
+func (*VMEvent) ProtoMessage()               {}
+func (*VMEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+func (m *VMEvent) GetType() VMEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return VMEvent_UNKNOWN
+}
+
+func (m *VMEvent) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *VMEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *VMEvent) GetVm() *VM {
+	if m != nil {
+		return m.Vm
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*VM)(nil), "api.VM")
+	proto.RegisterType((*ListFilter)(nil), "api.ListFilter")
+	proto.RegisterType((*ListVMRequest)(nil), "api.ListVMRequest")
+	proto.RegisterType((*ListVMReply)(nil), "api.ListVMReply")
+	proto.RegisterType((*GetVMRequest)(nil), "api.GetVMRequest")
+	proto.RegisterType((*FindRequest)(nil), "api.FindRequest")
+	proto.RegisterType((*CreateRequest)(nil), "api.CreateRequest")
+	proto.RegisterType((*Flavor)(nil), "api.Flavor")
+	proto.RegisterType((*ListFlavorsRequest)(nil), "api.ListFlavorsRequest")
+	proto.RegisterType((*ListFlavorsReply)(nil), "api.ListFlavorsReply")
+	proto.RegisterType((*DomainTemplate)(nil), "api.DomainTemplate")
+	proto.RegisterType((*ListTemplatesRequest)(nil), "api.ListTemplatesRequest")
+	proto.RegisterType((*ListTemplatesReply)(nil), "api.ListTemplatesReply")
+	proto.RegisterType((*DestroyRequest)(nil), "api.DestroyRequest")
+	proto.RegisterType((*ResizeDiskRequest)(nil), "api.ResizeDiskRequest")
+	proto.RegisterType((*ResizeRequest)(nil), "api.ResizeRequest")
+	proto.RegisterType((*ResizeReply)(nil), "api.ResizeReply")
+	proto.RegisterType((*Disk)(nil), "api.Disk")
+	proto.RegisterType((*AttachDiskRequest)(nil), "api.AttachDiskRequest")
+	proto.RegisterType((*DetachDiskRequest)(nil), "api.DetachDiskRequest")
+	proto.RegisterType((*ListDisksRequest)(nil), "api.ListDisksRequest")
+	proto.RegisterType((*ListDisksReply)(nil), "api.ListDisksReply")
+	proto.RegisterType((*Snapshot)(nil), "api.Snapshot")
+	proto.RegisterType((*CreateSnapshotRequest)(nil), "api.CreateSnapshotRequest")
+	proto.RegisterType((*RevertSnapshotRequest)(nil), "api.RevertSnapshotRequest")
+	proto.RegisterType((*DeleteSnapshotRequest)(nil), "api.DeleteSnapshotRequest")
+	proto.RegisterType((*ListSnapshotsRequest)(nil), "api.ListSnapshotsRequest")
+	proto.RegisterType((*ListSnapshotsReply)(nil), "api.ListSnapshotsReply")
+	proto.RegisterType((*Operation)(nil), "api.Operation")
+	proto.RegisterType((*Operation_Step)(nil), "api.Operation.Step")
+	proto.RegisterType((*GetOperationRequest)(nil), "api.GetOperationRequest")
+	proto.RegisterType((*ListOperationsRequest)(nil), "api.ListOperationsRequest")
+	proto.RegisterType((*ListOperationsReply)(nil), "api.ListOperationsReply")
+	proto.RegisterType((*WaitOperationRequest)(nil), "api.WaitOperationRequest")
+	proto.RegisterType((*CancelOperationRequest)(nil), "api.CancelOperationRequest")
+	proto.RegisterType((*StartRequest)(nil), "api.StartRequest")
+	proto.RegisterType((*StopRequest)(nil), "api.StopRequest")
+	proto.RegisterType((*RebootRequest)(nil), "api.RebootRequest")
+	proto.RegisterType((*ResetRequest)(nil), "api.ResetRequest")
+	proto.RegisterType((*SuspendRequest)(nil), "api.SuspendRequest")
+	proto.RegisterType((*ResumeRequest)(nil), "api.ResumeRequest")
+	proto.RegisterType((*UpdateLabelsRequest)(nil), "api.UpdateLabelsRequest")
+	proto.RegisterType((*WatchRequest)(nil), "api.WatchRequest")
+	proto.RegisterType((*VMEvent)(nil), "api.VMEvent")
+	proto.RegisterEnum("api.VM_State", VM_State_name, VM_State_value)
+	proto.RegisterEnum("api.FindRequest_FindBy", FindRequest_FindBy_name, FindRequest_FindBy_value)
+	proto.RegisterEnum("api.Operation_Kind", Operation_Kind_name, Operation_Kind_value)
+	proto.RegisterEnum("api.Operation_Status", Operation_Status_name, Operation_Status_value)
+	proto.RegisterEnum("api.VMEvent_Type", VMEvent_Type_name, VMEvent_Type_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for VMRegistry service
+
+type VMRegistryClient interface {
+	List(ctx context.Context, in *ListVMRequest, opts ...grpc.CallOption) (*ListVMReply, error)
+	Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*VM, error)
+	Get(ctx context.Context, in *GetVMRequest, opts ...grpc.CallOption) (*VM, error)
+	UpdateLabels(ctx context.Context, in *UpdateLabelsRequest, opts ...grpc.CallOption) (*VM, error)
+	ListFlavors(ctx context.Context, in *ListFlavorsRequest, opts ...grpc.CallOption) (*ListFlavorsReply, error)
+	ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesReply, error)
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Operation, error)
+	Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*Operation, error)
+	ResizeDisk(ctx context.Context, in *ResizeDiskRequest, opts ...grpc.CallOption) (*VM, error)
+	Resize(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*ResizeReply, error)
+	AttachDisk(ctx context.Context, in *AttachDiskRequest, opts ...grpc.CallOption) (*Disk, error)
+	DetachDisk(ctx context.Context, in *DetachDiskRequest, opts ...grpc.CallOption) (*Disk, error)
+	ListDisks(ctx context.Context, in *ListDisksRequest, opts ...grpc.CallOption) (*ListDisksReply, error)
+	CreateSnapshot(ctx context.Context, in *CreateSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	RevertSnapshot(ctx context.Context, in *RevertSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	DeleteSnapshot(ctx context.Context, in *DeleteSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*ListSnapshotsReply, error)
+	GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	ListOperations(ctx context.Context, in *ListOperationsRequest, opts ...grpc.CallOption) (*ListOperationsReply, error)
+	WaitOperation(ctx context.Context, in *WaitOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	CancelOperation(ctx context.Context, in *CancelOperationRequest, opts ...grpc.CallOption) (*Operation, error)
+	Start(ctx context.Context, in *StartRequest, opts ...grpc.CallOption) (*VM, error)
+	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*VM, error)
+	Reboot(ctx context.Context, in *RebootRequest, opts ...grpc.CallOption) (*VM, error)
+	Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*VM, error)
+	Suspend(ctx context.Context, in *SuspendRequest, opts ...grpc.CallOption) (*VM, error)
+	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*VM, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (VMRegistry_WatchClient, error)
+}
+
+type vMRegistryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewVMRegistryClient(cc *grpc.ClientConn) VMRegistryClient {
+	return &vMRegistryClient{cc}
+}
+
+func (c *vMRegistryClient) List(ctx context.Context, in *ListVMRequest, opts ...grpc.CallOption) (*ListVMReply, error) {
+	out := new(ListVMReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/List", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Find(ctx context.Context, in *FindRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Find", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Get(ctx context.Context, in *GetVMRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Get", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) UpdateLabels(ctx context.Context, in *UpdateLabelsRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/UpdateLabels", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) ListFlavors(ctx context.Context, in *ListFlavorsRequest, opts ...grpc.CallOption) (*ListFlavorsReply, error) {
+	out := new(ListFlavorsReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/ListFlavors", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) ListTemplates(ctx context.Context, in *ListTemplatesRequest, opts ...grpc.CallOption) (*ListTemplatesReply, error) {
+	out := new(ListTemplatesReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/ListTemplates", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Create", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Destroy(ctx context.Context, in *DestroyRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Destroy", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) ResizeDisk(ctx context.Context, in *ResizeDiskRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/ResizeDisk", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Resize(ctx context.Context, in *ResizeRequest, opts ...grpc.CallOption) (*ResizeReply, error) {
+	out := new(ResizeReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Resize", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) AttachDisk(ctx context.Context, in *AttachDiskRequest, opts ...grpc.CallOption) (*Disk, error) {
+	out := new(Disk)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/AttachDisk", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) DetachDisk(ctx context.Context, in *DetachDiskRequest, opts ...grpc.CallOption) (*Disk, error) {
+	out := new(Disk)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/DetachDisk", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) ListDisks(ctx context.Context, in *ListDisksRequest, opts ...grpc.CallOption) (*ListDisksReply, error) {
+	out := new(ListDisksReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/ListDisks", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) CreateSnapshot(ctx context.Context, in *CreateSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/CreateSnapshot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) RevertSnapshot(ctx context.Context, in *RevertSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/RevertSnapshot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) DeleteSnapshot(ctx context.Context, in *DeleteSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/DeleteSnapshot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) ListSnapshots(ctx context.Context, in *ListSnapshotsRequest, opts ...grpc.CallOption) (*ListSnapshotsReply, error) {
+	out := new(ListSnapshotsReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/ListSnapshots", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) GetOperation(ctx context.Context, in *GetOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/GetOperation", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) ListOperations(ctx context.Context, in *ListOperationsRequest, opts ...grpc.CallOption) (*ListOperationsReply, error) {
+	out := new(ListOperationsReply)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/ListOperations", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) WaitOperation(ctx context.Context, in *WaitOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/WaitOperation", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) CancelOperation(ctx context.Context, in *CancelOperationRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/CancelOperation", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Start(ctx context.Context, in *StartRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Start", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Stop", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Reboot(ctx context.Context, in *RebootRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Reboot", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Reset", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Suspend(ctx context.Context, in *SuspendRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Suspend", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*VM, error) {
+	out := new(VM)
+	err := grpc.Invoke(ctx, "/api.VMRegistry/Resume", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vMRegistryClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (VMRegistry_WatchClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_VMRegistry_serviceDesc.Streams[0], c.cc, "/api.VMRegistry/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vMRegistryWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VMRegistry_WatchClient interface {
+	Recv() (*VMEvent, error)
+	grpc.ClientStream
+}
+
+type vMRegistryWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *vMRegistryWatchClient) Recv() (*VMEvent, error) {
+	m := new(VMEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for VMRegistry service
+
+type VMRegistryServer interface {
+	List(context.Context, *ListVMRequest) (*ListVMReply, error)
+	Find(context.Context, *FindRequest) (*VM, error)
+	Get(context.Context, *GetVMRequest) (*VM, error)
+	UpdateLabels(context.Context, *UpdateLabelsRequest) (*VM, error)
+	ListFlavors(context.Context, *ListFlavorsRequest) (*ListFlavorsReply, error)
+	ListTemplates(context.Context, *ListTemplatesRequest) (*ListTemplatesReply, error)
+	Create(context.Context, *CreateRequest) (*Operation, error)
+	Destroy(context.Context, *DestroyRequest) (*Operation, error)
+	ResizeDisk(context.Context, *ResizeDiskRequest) (*VM, error)
+	Resize(context.Context, *ResizeRequest) (*ResizeReply, error)
+	AttachDisk(context.Context, *AttachDiskRequest) (*Disk, error)
+	DetachDisk(context.Context, *DetachDiskRequest) (*Disk, error)
+	ListDisks(context.Context, *ListDisksRequest) (*ListDisksReply, error)
+	CreateSnapshot(context.Context, *CreateSnapshotRequest) (*Snapshot, error)
+	RevertSnapshot(context.Context, *RevertSnapshotRequest) (*Snapshot, error)
+	DeleteSnapshot(context.Context, *DeleteSnapshotRequest) (*Snapshot, error)
+	ListSnapshots(context.Context, *ListSnapshotsRequest) (*ListSnapshotsReply, error)
+	GetOperation(context.Context, *GetOperationRequest) (*Operation, error)
+	ListOperations(context.Context, *ListOperationsRequest) (*ListOperationsReply, error)
+	WaitOperation(context.Context, *WaitOperationRequest) (*Operation, error)
+	CancelOperation(context.Context, *CancelOperationRequest) (*Operation, error)
+	Start(context.Context, *StartRequest) (*VM, error)
+	Stop(context.Context, *StopRequest) (*VM, error)
+	Reboot(context.Context, *RebootRequest) (*VM, error)
+	Reset(context.Context, *ResetRequest) (*VM, error)
+	Suspend(context.Context, *SuspendRequest) (*VM, error)
+	Resume(context.Context, *ResumeRequest) (*VM, error)
+	Watch(*WatchRequest, VMRegistry_WatchServer) error
+}
+
+func RegisterVMRegistryServer(s *grpc.Server, srv VMRegistryServer) {
+	s.RegisterService(&_VMRegistry_serviceDesc, srv)
+}
+
+func _VMRegistry_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVMRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).List(ctx, req.(*ListVMRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Find(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Find",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Find(ctx, req.(*FindRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVMRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Get(ctx, req.(*GetVMRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_UpdateLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateLabelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).UpdateLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/UpdateLabels",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).UpdateLabels(ctx, req.(*UpdateLabelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_ListFlavors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFlavorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).ListFlavors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/ListFlavors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).ListFlavors(ctx, req.(*ListFlavorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_ListTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTemplatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).ListTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/ListTemplates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).ListTemplates(ctx, req.(*ListTemplatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Destroy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DestroyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Destroy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Destroy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Destroy(ctx, req.(*DestroyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_ResizeDisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeDiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).ResizeDisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/ResizeDisk",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).ResizeDisk(ctx, req.(*ResizeDiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Resize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Resize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Resize",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Resize(ctx, req.(*ResizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
 
-type VMRegistryServer interface {
-	List(context.Context, *ListVMRequest) (*ListVMReply, error)
-	Find(context.Context, *FindRequest) (*VM, error)
-	Create(context.Context, *CreateRequest) (*VM, error)
-	Destroy(context.Context, *DestroyRequest) (*DestroyReply, error)
+func _VMRegistry_AttachDisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AttachDiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).AttachDisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/AttachDisk",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).AttachDisk(ctx, req.(*AttachDiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterVMRegistryServer(s *grpc.Server, srv VMRegistryServer) {
-	s.RegisterService(&_VMRegistry_serviceDesc, srv)
+func _VMRegistry_DetachDisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetachDiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).DetachDisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/DetachDisk",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).DetachDisk(ctx, req.(*DetachDiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _VMRegistry_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListVMRequest)
+func _VMRegistry_ListDisks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDisksRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(VMRegistryServer).List(ctx, in)
+		return srv.(VMRegistryServer).ListDisks(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.VMRegistry/List",
+		FullMethod: "/api.VMRegistry/ListDisks",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(VMRegistryServer).List(ctx, req.(*ListVMRequest))
+		return srv.(VMRegistryServer).ListDisks(ctx, req.(*ListDisksRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _VMRegistry_Find_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(FindRequest)
+func _VMRegistry_CreateSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSnapshotRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(VMRegistryServer).Find(ctx, in)
+		return srv.(VMRegistryServer).CreateSnapshot(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.VMRegistry/Find",
+		FullMethod: "/api.VMRegistry/CreateSnapshot",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(VMRegistryServer).Find(ctx, req.(*FindRequest))
+		return srv.(VMRegistryServer).CreateSnapshot(ctx, req.(*CreateSnapshotRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _VMRegistry_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateRequest)
+func _VMRegistry_RevertSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevertSnapshotRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(VMRegistryServer).Create(ctx, in)
+		return srv.(VMRegistryServer).RevertSnapshot(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.VMRegistry/Create",
+		FullMethod: "/api.VMRegistry/RevertSnapshot",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(VMRegistryServer).Create(ctx, req.(*CreateRequest))
+		return srv.(VMRegistryServer).RevertSnapshot(ctx, req.(*RevertSnapshotRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _VMRegistry_Destroy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DestroyRequest)
+func _VMRegistry_DeleteSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSnapshotRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(VMRegistryServer).Destroy(ctx, in)
+		return srv.(VMRegistryServer).DeleteSnapshot(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/api.VMRegistry/Destroy",
+		FullMethod: "/api.VMRegistry/DeleteSnapshot",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(VMRegistryServer).Destroy(ctx, req.(*DestroyRequest))
+		return srv.(VMRegistryServer).DeleteSnapshot(ctx, req.(*DeleteSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_ListSnapshots_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSnapshotsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).ListSnapshots(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/ListSnapshots",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).ListSnapshots(ctx, req.(*ListSnapshotsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_GetOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).GetOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/GetOperation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).GetOperation(ctx, req.(*GetOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_ListOperations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOperationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).ListOperations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/ListOperations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).ListOperations(ctx, req.(*ListOperationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_WaitOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WaitOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).WaitOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/WaitOperation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).WaitOperation(ctx, req.(*WaitOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_CancelOperation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).CancelOperation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/CancelOperation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).CancelOperation(ctx, req.(*CancelOperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Start",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Start(ctx, req.(*StartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Stop",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Stop(ctx, req.(*StopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Reboot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebootRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Reboot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Reboot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Reboot(ctx, req.(*RebootRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Reset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Reset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Reset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Reset(ctx, req.(*ResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Suspend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuspendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Suspend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Suspend",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Suspend(ctx, req.(*SuspendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VMRegistry_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VMRegistryServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.VMRegistry/Resume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VMRegistryServer).Resume(ctx, req.(*ResumeRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VMRegistry_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VMRegistryServer).Watch(m, &vMRegistryWatchServer{stream})
+}
+
+type VMRegistry_WatchServer interface {
+	Send(*VMEvent) error
+	grpc.ServerStream
+}
+
+type vMRegistryWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *vMRegistryWatchServer) Send(m *VMEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _VMRegistry_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.VMRegistry",
 	HandlerType: (*VMRegistryServer)(nil),
@@ -402,6 +2557,22 @@ var _VMRegistry_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Find",
 			Handler:    _VMRegistry_Find_Handler,
 		},
+		{
+			MethodName: "Get",
+			Handler:    _VMRegistry_Get_Handler,
+		},
+		{
+			MethodName: "UpdateLabels",
+			Handler:    _VMRegistry_UpdateLabels_Handler,
+		},
+		{
+			MethodName: "ListFlavors",
+			Handler:    _VMRegistry_ListFlavors_Handler,
+		},
+		{
+			MethodName: "ListTemplates",
+			Handler:    _VMRegistry_ListTemplates_Handler,
+		},
 		{
 			MethodName: "Create",
 			Handler:    _VMRegistry_Create_Handler,
@@ -410,38 +2581,231 @@ var _VMRegistry_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Destroy",
 			Handler:    _VMRegistry_Destroy_Handler,
 		},
+		{
+			MethodName: "ResizeDisk",
+			Handler:    _VMRegistry_ResizeDisk_Handler,
+		},
+		{
+			MethodName: "Resize",
+			Handler:    _VMRegistry_Resize_Handler,
+		},
+		{
+			MethodName: "AttachDisk",
+			Handler:    _VMRegistry_AttachDisk_Handler,
+		},
+		{
+			MethodName: "DetachDisk",
+			Handler:    _VMRegistry_DetachDisk_Handler,
+		},
+		{
+			MethodName: "ListDisks",
+			Handler:    _VMRegistry_ListDisks_Handler,
+		},
+		{
+			MethodName: "CreateSnapshot",
+			Handler:    _VMRegistry_CreateSnapshot_Handler,
+		},
+		{
+			MethodName: "RevertSnapshot",
+			Handler:    _VMRegistry_RevertSnapshot_Handler,
+		},
+		{
+			MethodName: "DeleteSnapshot",
+			Handler:    _VMRegistry_DeleteSnapshot_Handler,
+		},
+		{
+			MethodName: "ListSnapshots",
+			Handler:    _VMRegistry_ListSnapshots_Handler,
+		},
+		{
+			MethodName: "GetOperation",
+			Handler:    _VMRegistry_GetOperation_Handler,
+		},
+		{
+			MethodName: "ListOperations",
+			Handler:    _VMRegistry_ListOperations_Handler,
+		},
+		{
+			MethodName: "WaitOperation",
+			Handler:    _VMRegistry_WaitOperation_Handler,
+		},
+		{
+			MethodName: "CancelOperation",
+			Handler:    _VMRegistry_CancelOperation_Handler,
+		},
+		{
+			MethodName: "Start",
+			Handler:    _VMRegistry_Start_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _VMRegistry_Stop_Handler,
+		},
+		{
+			MethodName: "Reboot",
+			Handler:    _VMRegistry_Reboot_Handler,
+		},
+		{
+			MethodName: "Reset",
+			Handler:    _VMRegistry_Reset_Handler,
+		},
+		{
+			MethodName: "Suspend",
+			Handler:    _VMRegistry_Suspend_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _VMRegistry_Resume_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _VMRegistry_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "vmregistry.proto",
 }
 
 func init() { proto.RegisterFile("vmregistry.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 393 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0x4f, 0xab, 0xd3, 0x40,
-	0x14, 0xc5, 0x9b, 0x3f, 0x2f, 0xc1, 0x9b, 0xd7, 0xbc, 0x78, 0x15, 0x8c, 0x5d, 0x3d, 0x47, 0xc1,
-	0xe2, 0x22, 0x48, 0xdf, 0xce, 0x9d, 0xf6, 0x0f, 0x04, 0x8c, 0x94, 0x88, 0xdd, 0x96, 0xb4, 0x9d,
-	0x96, 0x81, 0xa4, 0x19, 0x33, 0x69, 0x21, 0xee, 0xf4, 0x8b, 0xf9, 0xd5, 0x64, 0x6e, 0x52, 0x69,
-	0x04, 0xdf, 0x2a, 0x67, 0x0e, 0x67, 0x66, 0x4e, 0x7e, 0x77, 0x20, 0x38, 0x17, 0x15, 0x3f, 0x08,
-	0x55, 0x57, 0x4d, 0x24, 0xab, 0xb2, 0x2e, 0xd1, 0xca, 0xa4, 0x60, 0x1f, 0xc0, 0x5c, 0x25, 0x88,
-	0x60, 0x1f, 0xb3, 0x82, 0x87, 0xc6, 0xbd, 0x31, 0x7e, 0x92, 0x92, 0xc6, 0x00, 0xac, 0x22, 0xdb,
-	0x86, 0x26, 0x59, 0x5a, 0xa2, 0x0f, 0xa6, 0x90, 0xa1, 0x45, 0x86, 0x29, 0x24, 0xbb, 0x83, 0xe1,
-	0x67, 0xa1, 0xea, 0x55, 0x92, 0xf2, 0xef, 0x27, 0xae, 0x6a, 0x36, 0x06, 0xef, 0x62, 0xc8, 0xbc,
-	0xc1, 0x97, 0x60, 0x9d, 0x0b, 0x15, 0x1a, 0xf7, 0xd6, 0xd8, 0x9b, 0xb8, 0x51, 0x26, 0x45, 0xb4,
-	0x4a, 0x52, 0xed, 0xb1, 0x9f, 0x06, 0x78, 0x0b, 0x71, 0xdc, 0x75, 0x3b, 0xf1, 0x3d, 0xb8, 0x7b,
-	0x71, 0xdc, 0xad, 0x37, 0x0d, 0x75, 0xf0, 0x27, 0x2f, 0x28, 0x7e, 0x15, 0x21, 0xfd, 0xa9, 0x49,
-	0x9d, 0x3d, 0x7d, 0xf1, 0x39, 0xdc, 0x9c, 0xb3, 0xfc, 0xc4, 0xbb, 0x82, 0xed, 0x82, 0xbd, 0x03,
-	0xa7, 0xcd, 0xe1, 0x1d, 0x78, 0xdf, 0xbe, 0x7c, 0x5d, 0xce, 0xa7, 0xf1, 0x22, 0x9e, 0xcf, 0x82,
-	0x01, 0x3a, 0x60, 0xc6, 0xcb, 0xc0, 0x40, 0x17, 0xac, 0xe4, 0xe3, 0x34, 0x30, 0xd9, 0x2f, 0x03,
-	0x86, 0xd3, 0x8a, 0x67, 0x35, 0xbf, 0xb4, 0xf8, 0x1f, 0x06, 0x5e, 0xd0, 0x2d, 0x76, 0xaa, 0xa5,
-	0xbe, 0x79, 0x5b, 0x56, 0x5c, 0x11, 0x89, 0x61, 0xda, 0x2e, 0xf4, 0x5e, 0x25, 0x7e, 0xf0, 0xd0,
-	0xa6, 0x20, 0x69, 0x7c, 0x05, 0xb7, 0xaa, 0x3c, 0x55, 0x5b, 0xbe, 0x16, 0x45, 0x76, 0xe0, 0xe1,
-	0x0d, 0x9d, 0xeb, 0xb5, 0x5e, 0xac, 0x2d, 0xf6, 0x06, 0xfc, 0x19, 0x57, 0x75, 0x55, 0x36, 0x8f,
-	0x94, 0x60, 0x3e, 0xdc, 0xfe, 0x4d, 0xc9, 0xbc, 0x99, 0xfc, 0x36, 0x00, 0x34, 0xe5, 0x76, 0x9e,
-	0x18, 0x81, 0xad, 0xb9, 0x23, 0x12, 0xb4, 0xde, 0x4c, 0x46, 0x41, 0xcf, 0x93, 0x79, 0xc3, 0x06,
-	0xf8, 0x1a, 0x6c, 0x4d, 0x09, 0x83, 0x7f, 0x21, 0x8f, 0x2e, 0x53, 0x62, 0x03, 0x7c, 0x0b, 0x4e,
-	0x4b, 0xa7, 0x3b, 0xb6, 0x87, 0xea, 0x3a, 0xf8, 0x00, 0x6e, 0x57, 0x0e, 0x9f, 0x91, 0xdb, 0xff,
-	0xa1, 0xd1, 0xd3, 0xbe, 0x49, 0x15, 0x36, 0x0e, 0xbd, 0xc1, 0x87, 0x3f, 0x01, 0x00, 0x00, 0xff,
-	0xff, 0xc4, 0x21, 0x49, 0x05, 0x97, 0x02, 0x00, 0x00,
+	// 2167 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x39, 0xcd, 0x72, 0xdb, 0xc8,
+	0xd1, 0x02, 0x08, 0xfe, 0x35, 0x45, 0x8a, 0x1e, 0xc9, 0x16, 0xcc, 0xfd, 0xfc, 0x45, 0x0b, 0x47,
+	0x6b, 0x79, 0x77, 0xc3, 0xd8, 0x72, 0x95, 0x2b, 0xde, 0x24, 0x95, 0xa5, 0x49, 0x4a, 0x56, 0x49,
+	0xa2, 0xb4, 0xa0, 0x64, 0x57, 0x4e, 0x2c, 0x98, 0x1c, 0x49, 0x88, 0x48, 0x02, 0x8b, 0x19, 0x32,
+	0xe1, 0x5e, 0x52, 0xb9, 0xe5, 0x98, 0xf7, 0x48, 0x55, 0x1e, 0x20, 0xaf, 0x90, 0x53, 0xf2, 0x0e,
+	0x79, 0x8f, 0x54, 0xcf, 0x0c, 0x40, 0x80, 0x84, 0x68, 0x27, 0xeb, 0x93, 0xd0, 0x3d, 0x3d, 0x3d,
+	0xfd, 0xff, 0x43, 0x41, 0x75, 0x3a, 0x0a, 0xe8, 0xb5, 0xcb, 0x78, 0x30, 0xab, 0xfb, 0x81, 0xc7,
+	0x3d, 0x92, 0x71, 0x7c, 0xd7, 0xfa, 0xab, 0x01, 0xfa, 0xdb, 0x53, 0x42, 0xc0, 0x18, 0x3b, 0x23,
+	0x6a, 0x6a, 0x3b, 0xda, 0x5e, 0xd1, 0x16, 0xdf, 0xa4, 0x0a, 0x99, 0x91, 0xd3, 0x37, 0x75, 0x81,
+	0xc2, 0x4f, 0x52, 0x01, 0xdd, 0xf5, 0xcd, 0x8c, 0x40, 0xe8, 0xae, 0x2f, 0x28, 0xe8, 0xc8, 0x34,
+	0x76, 0xb4, 0x3d, 0xc3, 0xc6, 0x4f, 0xb2, 0x05, 0xd9, 0xbe, 0x17, 0x50, 0x66, 0x66, 0x77, 0xb4,
+	0xbd, 0xb2, 0x2d, 0x01, 0xe4, 0xce, 0xdc, 0x1f, 0xa8, 0x99, 0x13, 0x84, 0xe2, 0x9b, 0x7c, 0x0e,
+	0xeb, 0xcc, 0x9b, 0x04, 0x7d, 0xda, 0x73, 0x47, 0xce, 0x35, 0x35, 0xf3, 0x82, 0x6b, 0x49, 0xe2,
+	0x8e, 0x10, 0x45, 0x1e, 0x43, 0x96, 0x71, 0x87, 0x53, 0xb3, 0xb0, 0xa3, 0xed, 0x55, 0xf6, 0xcb,
+	0x75, 0xc7, 0x77, 0xeb, 0x6f, 0x4f, 0xeb, 0x5d, 0x44, 0xda, 0xf2, 0x0c, 0x79, 0x8f, 0x9c, 0x3e,
+	0x33, 0x8b, 0x3b, 0x19, 0x94, 0x1c, 0xbf, 0x89, 0x09, 0xf9, 0x7e, 0x40, 0x1d, 0x4e, 0x07, 0x26,
+	0xec, 0x68, 0x7b, 0x19, 0x3b, 0x04, 0xf1, 0x64, 0x4c, 0xf9, 0xef, 0xbd, 0xe0, 0xd6, 0x2c, 0x89,
+	0x07, 0x43, 0x10, 0xf9, 0xb8, 0xfe, 0xf4, 0xa5, 0xb9, 0x2e, 0x2d, 0x80, 0xdf, 0xe4, 0x2b, 0xc8,
+	0x0d, 0x9d, 0xf7, 0x74, 0xc8, 0xcc, 0xf2, 0x4e, 0x66, 0xaf, 0xb4, 0xbf, 0x19, 0x4a, 0x70, 0x22,
+	0xb0, 0xed, 0x31, 0x0f, 0x66, 0xb6, 0x22, 0x21, 0x0f, 0x20, 0x77, 0x35, 0x74, 0xa6, 0x5e, 0x60,
+	0x56, 0x04, 0x0b, 0x05, 0x91, 0x1a, 0x14, 0x38, 0x1d, 0xf9, 0x43, 0x54, 0x64, 0x43, 0x9c, 0x44,
+	0x70, 0xed, 0x15, 0x94, 0x62, 0xac, 0xd0, 0x9e, 0xb7, 0x74, 0xa6, 0x9c, 0x80, 0x9f, 0x68, 0xcf,
+	0xa9, 0x33, 0x9c, 0x50, 0xe5, 0x05, 0x09, 0x7c, 0xa3, 0xff, 0x42, 0xb3, 0x18, 0x64, 0x85, 0x1d,
+	0x48, 0x09, 0xf2, 0x9d, 0xb3, 0xee, 0x45, 0xe3, 0xa2, 0x5d, 0x5d, 0x43, 0xc0, 0xbe, 0xec, 0x74,
+	0x8e, 0x3a, 0x87, 0x55, 0x0d, 0x81, 0xd7, 0x27, 0x67, 0xcd, 0xe3, 0x76, 0xab, 0xaa, 0x13, 0x80,
+	0xdc, 0x79, 0xe3, 0xb2, 0xdb, 0x6e, 0x55, 0x33, 0x64, 0x1d, 0x0a, 0xdd, 0x37, 0x97, 0x17, 0xad,
+	0xb3, 0x77, 0x9d, 0xaa, 0x81, 0x64, 0x08, 0x9d, 0x1d, 0x1c, 0x54, 0xb3, 0x08, 0x34, 0xed, 0x46,
+	0xf7, 0x4d, 0xbb, 0x55, 0xcd, 0x91, 0x0d, 0x28, 0x9d, 0x9f, 0x76, 0x2f, 0xbb, 0xe7, 0xed, 0x4e,
+	0xab, 0xdd, 0xaa, 0xe6, 0xad, 0x3f, 0x6b, 0x00, 0x27, 0x2e, 0xe3, 0x07, 0xee, 0x90, 0xd3, 0x20,
+	0x35, 0x6a, 0x76, 0x21, 0x27, 0x1c, 0xc3, 0x4c, 0x7d, 0x27, 0xb3, 0xec, 0x35, 0x75, 0x18, 0x77,
+	0x44, 0x26, 0xe9, 0x88, 0x5d, 0xa8, 0x08, 0x8b, 0xf6, 0x18, 0x1d, 0xd2, 0x3e, 0xf7, 0x02, 0x11,
+	0x5f, 0x45, 0xbb, 0x2c, 0xb0, 0x5d, 0x85, 0xb4, 0xfe, 0xae, 0x41, 0x19, 0x45, 0x79, 0x7b, 0x6a,
+	0xd3, 0xef, 0x27, 0x94, 0x71, 0xf2, 0x74, 0xe9, 0xa2, 0x90, 0xeb, 0xb5, 0x6e, 0x6a, 0x0b, 0x97,
+	0xc9, 0x13, 0xc8, 0x5d, 0x09, 0x15, 0x84, 0x5d, 0x4b, 0xfb, 0x1b, 0x42, 0xc8, 0xb9, 0x66, 0xb6,
+	0x3a, 0x26, 0x0f, 0xa1, 0xe0, 0x05, 0x03, 0x1a, 0xf4, 0xde, 0xcf, 0x42, 0x39, 0x05, 0xfc, 0x7a,
+	0x46, 0x3e, 0x83, 0xa2, 0xef, 0x5c, 0xd3, 0x9e, 0x88, 0x6c, 0x43, 0x84, 0x7b, 0x01, 0x11, 0x5d,
+	0x8c, 0xee, 0x47, 0x00, 0xe2, 0x90, 0x7b, 0xb7, 0x74, 0x2c, 0x92, 0xa1, 0x68, 0x0b, 0xf2, 0x0b,
+	0x44, 0x58, 0xe7, 0x50, 0x0a, 0x65, 0xf7, 0x87, 0x33, 0xf2, 0x10, 0x32, 0xd3, 0x11, 0x33, 0x35,
+	0x11, 0x64, 0x79, 0x65, 0x30, 0x1b, 0x71, 0xe4, 0x0b, 0xd8, 0x18, 0xd3, 0x3f, 0xf0, 0x5e, 0x8c,
+	0x9b, 0x0c, 0x85, 0x32, 0xa2, 0xcf, 0x23, 0x8e, 0x16, 0xac, 0x1f, 0xd2, 0x98, 0x31, 0x52, 0x5c,
+	0x63, 0xfd, 0x49, 0x83, 0xd2, 0x81, 0x3b, 0x1e, 0x84, 0x34, 0xcf, 0x20, 0x7f, 0xe5, 0x8e, 0x07,
+	0xa8, 0x9b, 0x26, 0x32, 0x6c, 0x5b, 0x3c, 0x1d, 0x23, 0x11, 0xdf, 0xaf, 0x67, 0x68, 0x0e, 0xfc,
+	0x9b, 0x1e, 0x8e, 0xd6, 0x97, 0x90, 0x93, 0x74, 0x18, 0x30, 0x97, 0x9d, 0xee, 0x79, 0xbb, 0x79,
+	0x74, 0x70, 0xd4, 0x6e, 0x55, 0xd7, 0x48, 0x0e, 0xf4, 0xa3, 0xf3, 0xaa, 0x46, 0xf2, 0x90, 0x39,
+	0x6d, 0x34, 0xab, 0xba, 0xf5, 0x8f, 0x0c, 0x94, 0x9b, 0x22, 0x19, 0x57, 0x48, 0x1a, 0x16, 0x16,
+	0x3d, 0xa5, 0xb0, 0x64, 0xd2, 0x0a, 0x8b, 0xb1, 0xa2, 0xb0, 0x64, 0x97, 0x0b, 0x4b, 0x2c, 0xf8,
+	0x72, 0xc9, 0xe0, 0x7b, 0x04, 0x10, 0x48, 0xb9, 0x7a, 0xee, 0x40, 0xd5, 0xa4, 0xa2, 0xc2, 0x1c,
+	0x0d, 0xc8, 0xcb, 0xa8, 0x20, 0x14, 0x84, 0xaf, 0xfe, 0x5f, 0x18, 0x2c, 0xa1, 0xcf, 0x07, 0x6a,
+	0x43, 0xf1, 0xce, 0xda, 0x00, 0xc9, 0xda, 0x40, 0xea, 0xb0, 0xc9, 0xd8, 0x4d, 0xcf, 0x99, 0xf0,
+	0x1b, 0x2f, 0x70, 0x7f, 0xa0, 0x83, 0xde, 0x2d, 0x9d, 0x31, 0xb3, 0x24, 0xea, 0xdc, 0x3d, 0xc6,
+	0x6e, 0x1a, 0xd1, 0xc9, 0x31, 0x9d, 0x31, 0x8c, 0xc7, 0x09, 0xa3, 0x41, 0x6f, 0xe0, 0x70, 0x47,
+	0x55, 0xb1, 0x02, 0x22, 0x5a, 0x0e, 0x77, 0xf0, 0xa1, 0x1b, 0x8f, 0x71, 0x61, 0xe8, 0xb2, 0x3c,
+	0x0b, 0xe1, 0x1f, 0x53, 0x84, 0x38, 0xe4, 0x0e, 0xa4, 0x26, 0x9f, 0xda, 0x8b, 0x71, 0xcb, 0x64,
+	0x93, 0x96, 0xb1, 0xb6, 0x80, 0x88, 0x54, 0x15, 0x2f, 0x33, 0x65, 0x77, 0xeb, 0x15, 0x54, 0x13,
+	0x58, 0x4c, 0xac, 0x5d, 0xc8, 0x4b, 0x4b, 0x87, 0xc9, 0x55, 0x92, 0x11, 0x2e, 0x70, 0x76, 0x78,
+	0x66, 0x35, 0xa1, 0xd2, 0xf2, 0x46, 0x8e, 0x3b, 0xbe, 0x08, 0x8d, 0x9f, 0xa6, 0xce, 0x23, 0x00,
+	0x97, 0xf5, 0x06, 0xf4, 0xca, 0x99, 0x0c, 0xb9, 0xd0, 0xaa, 0x60, 0x17, 0x5d, 0xd6, 0x92, 0x08,
+	0xeb, 0x01, 0x6c, 0xe1, 0xfb, 0x21, 0x8b, 0x48, 0xae, 0x43, 0x20, 0x0b, 0x78, 0x94, 0xec, 0x39,
+	0x14, 0x43, 0x7d, 0x42, 0xd9, 0x64, 0x77, 0x49, 0x0a, 0x62, 0xcf, 0xa9, 0x84, 0x94, 0x94, 0xf1,
+	0xc0, 0x9b, 0xad, 0x4a, 0x9d, 0x64, 0x04, 0xeb, 0x0b, 0x11, 0x6c, 0x7d, 0x07, 0xf7, 0x6c, 0x8a,
+	0x16, 0x6e, 0xb9, 0xec, 0x76, 0x15, 0x9f, 0xd0, 0x29, 0x7a, 0xcc, 0x29, 0x5b, 0x90, 0xbd, 0xf2,
+	0x82, 0x3e, 0x15, 0xee, 0x2b, 0xd8, 0x12, 0xb0, 0x8e, 0xa1, 0x2c, 0x59, 0x7e, 0x82, 0x8c, 0xb6,
+	0xce, 0xa0, 0x14, 0x32, 0x43, 0x33, 0x6d, 0x83, 0x3e, 0x1d, 0x09, 0x46, 0xb1, 0xc2, 0xa8, 0x4f,
+	0x47, 0xe4, 0x09, 0x6c, 0x04, 0xf4, 0xbd, 0xe7, 0xf1, 0x1e, 0xea, 0xe6, 0x06, 0x74, 0xa0, 0x3c,
+	0x52, 0x91, 0x68, 0x5b, 0x61, 0xad, 0xbf, 0x68, 0x60, 0xa0, 0xae, 0x1f, 0xad, 0xe4, 0x03, 0xc8,
+	0x71, 0x27, 0xb8, 0xa6, 0x5c, 0x15, 0x7c, 0x05, 0x21, 0x3e, 0xa0, 0xdc, 0x71, 0xc7, 0x22, 0x4e,
+	0x0b, 0xb6, 0x82, 0x90, 0x87, 0xef, 0xf0, 0x1b, 0x15, 0xa5, 0xe2, 0x9b, 0xfc, 0x1f, 0x14, 0x07,
+	0x94, 0x3b, 0xfd, 0x1b, 0x77, 0x7c, 0x2d, 0x4a, 0x4c, 0xc1, 0x9e, 0x23, 0xac, 0x6b, 0xb8, 0xd7,
+	0xe0, 0x08, 0x7c, 0x84, 0x0f, 0x06, 0x2e, 0xbb, 0x55, 0x5e, 0x14, 0xdf, 0x91, 0xc8, 0x99, 0xa4,
+	0xc8, 0x69, 0xa2, 0x59, 0xbf, 0x84, 0x7b, 0x2d, 0xfa, 0x3f, 0x3e, 0x64, 0x7d, 0x21, 0xf3, 0x09,
+	0xaf, 0xb2, 0x55, 0x5d, 0xe5, 0x39, 0x54, 0x62, 0x74, 0xe8, 0xb4, 0x9f, 0x40, 0x16, 0x39, 0x84,
+	0x71, 0x5d, 0x94, 0x71, 0x8d, 0x22, 0x48, 0xbc, 0xf5, 0x3b, 0x28, 0x74, 0xc7, 0x8e, 0xcf, 0x6e,
+	0xbc, 0x74, 0x71, 0x62, 0xf3, 0x9b, 0x9e, 0x9c, 0xdf, 0xb6, 0x42, 0xd6, 0x19, 0x51, 0x06, 0x25,
+	0x80, 0xc5, 0xe2, 0xfb, 0x89, 0x4b, 0x59, 0x9f, 0x0e, 0x94, 0x05, 0x22, 0xd8, 0x3a, 0x84, 0xfb,
+	0xb2, 0x3e, 0x87, 0x2f, 0xae, 0xb2, 0x43, 0x0d, 0x0a, 0x4c, 0x91, 0x29, 0x5b, 0x44, 0x30, 0x32,
+	0xb2, 0xe9, 0x94, 0x06, 0xfc, 0x13, 0x30, 0x6a, 0xd1, 0x21, 0xfd, 0xf1, 0x12, 0x7d, 0x29, 0x2b,
+	0x4e, 0xc8, 0x66, 0xa5, 0x97, 0x1a, 0x40, 0x16, 0x68, 0xd1, 0x53, 0x5f, 0x41, 0x31, 0xe4, 0x16,
+	0x7a, 0x4b, 0xce, 0x6b, 0x91, 0x68, 0xf3, 0x73, 0xeb, 0x5f, 0x06, 0x14, 0xcf, 0x7c, 0x1a, 0x38,
+	0xdc, 0xf5, 0xc6, 0x62, 0x17, 0x18, 0xa8, 0x27, 0x74, 0x77, 0x40, 0x9e, 0x80, 0x71, 0xeb, 0x8e,
+	0xa5, 0xc3, 0x2a, 0xaa, 0x96, 0x45, 0xd4, 0xf5, 0x63, 0x9c, 0x29, 0x04, 0x01, 0xd9, 0x86, 0xfc,
+	0x74, 0xd4, 0x13, 0x02, 0xaa, 0x04, 0x9b, 0x8e, 0x3a, 0xa8, 0xea, 0xcf, 0xe4, 0xe4, 0x38, 0x61,
+	0xc2, 0x87, 0x95, 0xfd, 0xfb, 0x0b, 0x3c, 0xba, 0xe2, 0xd0, 0x56, 0x44, 0xe4, 0x29, 0x6e, 0x07,
+	0xd4, 0xc7, 0x55, 0x63, 0x5e, 0x3d, 0xe3, 0xd4, 0xd4, 0xb7, 0x25, 0x45, 0x3c, 0x9e, 0x72, 0xc9,
+	0x78, 0xaa, 0x41, 0xe1, 0xca, 0x1d, 0xbb, 0xec, 0x86, 0xca, 0x6e, 0x9f, 0xb1, 0x23, 0x58, 0xd5,
+	0x9e, 0xc2, 0x72, 0xed, 0x79, 0x04, 0x40, 0x83, 0xc0, 0x0b, 0x7a, 0x7d, 0x6f, 0x40, 0x45, 0x47,
+	0xcf, 0xda, 0x45, 0x81, 0x69, 0x7a, 0x03, 0x51, 0x25, 0x05, 0xa0, 0x3a, 0xba, 0x04, 0x16, 0xea,
+	0x72, 0x69, 0xa1, 0x2e, 0xd7, 0xfe, 0x08, 0x06, 0x4a, 0x9c, 0x1a, 0x03, 0x73, 0xc3, 0xe8, 0x1f,
+	0x63, 0x18, 0x13, 0xf2, 0x8c, 0x3b, 0x01, 0x6a, 0x9b, 0x91, 0xda, 0x2a, 0x30, 0xa1, 0xad, 0x91,
+	0xd4, 0xd6, 0xfa, 0x1a, 0x0c, 0x74, 0x12, 0x2e, 0x00, 0x97, 0x9d, 0xe3, 0x0e, 0xae, 0x06, 0x6b,
+	0xb8, 0x34, 0x34, 0xed, 0x36, 0xae, 0x16, 0x62, 0x9b, 0x68, 0xb5, 0xbb, 0x17, 0xf6, 0xd9, 0x6f,
+	0xab, 0xba, 0x75, 0x08, 0x39, 0xf9, 0x2a, 0xa2, 0x71, 0x3d, 0xc0, 0x8d, 0x63, 0x61, 0xfd, 0x28,
+	0x80, 0xd1, 0x3a, 0xeb, 0xb4, 0xe5, 0xee, 0x71, 0xd0, 0x38, 0x3a, 0x11, 0xbb, 0x47, 0x19, 0x8a,
+	0xcd, 0x46, 0xa7, 0xd9, 0x3e, 0x41, 0xd0, 0xb0, 0x76, 0x61, 0xf3, 0x90, 0xf2, 0x48, 0x97, 0x30,
+	0x84, 0x17, 0xa2, 0xcb, 0x7a, 0x06, 0xf7, 0x31, 0x7c, 0x23, 0xba, 0x28, 0xd6, 0x63, 0xd1, 0xa4,
+	0xc5, 0xa3, 0xc9, 0x6a, 0xc3, 0xe6, 0xe2, 0x0d, 0x8c, 0xf8, 0x3a, 0x80, 0x17, 0xa1, 0x54, 0xc8,
+	0x57, 0x92, 0xf6, 0xb4, 0x63, 0x14, 0xd6, 0xb7, 0xb0, 0xf5, 0xce, 0x71, 0x3f, 0x28, 0x20, 0x1a,
+	0x9d, 0xbb, 0x23, 0xea, 0x4d, 0x64, 0x9a, 0x96, 0xed, 0x10, 0xb4, 0xf6, 0xe0, 0x41, 0xd3, 0x19,
+	0xf7, 0xe9, 0xf0, 0x83, 0x4a, 0x5a, 0xb0, 0xde, 0x45, 0x4f, 0xad, 0xca, 0xe3, 0xef, 0xa0, 0xd4,
+	0xe5, 0x9e, 0xbf, 0x82, 0xe4, 0x6e, 0x51, 0xee, 0xe8, 0xdf, 0x8f, 0xb1, 0x7f, 0x87, 0x3d, 0xf3,
+	0xae, 0x77, 0x2d, 0x58, 0xb7, 0x29, 0xa3, 0x2b, 0x69, 0x7e, 0x0a, 0x95, 0xee, 0x84, 0xf9, 0x74,
+	0x3c, 0x58, 0x45, 0x25, 0x9e, 0x63, 0x93, 0xd1, 0xaa, 0x71, 0xc1, 0xfa, 0x9b, 0x06, 0x9b, 0x97,
+	0xfe, 0xc0, 0xe1, 0x54, 0x8e, 0xa6, 0xab, 0xf4, 0x7d, 0x01, 0x19, 0x46, 0xb9, 0x58, 0x37, 0x4b,
+	0xfb, 0x9f, 0x0b, 0x5f, 0xa6, 0x5c, 0xad, 0x77, 0x29, 0x97, 0x43, 0x39, 0x52, 0xcb, 0x96, 0x39,
+	0xf2, 0xa6, 0x54, 0x75, 0x12, 0x05, 0xd5, 0x5e, 0x42, 0x21, 0x24, 0xfc, 0xaf, 0x26, 0x61, 0x0b,
+	0xd6, 0xdf, 0x39, 0xbc, 0x7f, 0xb3, 0x4a, 0xa9, 0x7f, 0x6b, 0x90, 0x7f, 0x7b, 0xda, 0x9e, 0xd2,
+	0x31, 0x27, 0xbb, 0x60, 0xf0, 0x99, 0x4f, 0xd5, 0xe2, 0x75, 0x4f, 0x95, 0x17, 0x71, 0x56, 0xbf,
+	0x98, 0xf9, 0xd4, 0x16, 0xc7, 0x11, 0x1b, 0x3d, 0xd9, 0xac, 0xd1, 0xa1, 0x2a, 0xb9, 0xc5, 0xb7,
+	0xaa, 0x55, 0xc6, 0x52, 0xad, 0xb2, 0x02, 0x30, 0x90, 0x5d, 0x32, 0xad, 0xc5, 0x92, 0x8f, 0x69,
+	0xdd, 0x92, 0x79, 0xdd, 0xbd, 0x68, 0xd8, 0x17, 0xe2, 0x57, 0x02, 0x01, 0x9c, 0x9d, 0x9f, 0x87,
+	0xa9, 0xaa, 0x32, 0x1e, 0x53, 0x35, 0xfe, 0xd3, 0x40, 0x16, 0xcf, 0xe6, 0x3f, 0x0c, 0xe4, 0xf0,
+	0xcc, 0x6e, 0x77, 0x2f, 0x4f, 0xf1, 0x57, 0x82, 0xfd, 0x7f, 0x96, 0x00, 0x70, 0x13, 0x95, 0xbf,
+	0x36, 0x91, 0x3a, 0x18, 0x98, 0x89, 0x84, 0x44, 0x4b, 0x76, 0xb4, 0xa6, 0xd6, 0xaa, 0x09, 0x9c,
+	0x3f, 0x9c, 0x59, 0x6b, 0xe4, 0x31, 0x18, 0xb8, 0x4e, 0x92, 0xea, 0xe2, 0x36, 0x5a, 0x0b, 0x35,
+	0x13, 0x44, 0x99, 0x43, 0xca, 0x89, 0x34, 0x5c, 0x7c, 0xf3, 0x8d, 0x13, 0xbd, 0x80, 0xf5, 0x78,
+	0x24, 0x10, 0xf3, 0xae, 0xe0, 0x88, 0x5f, 0xfa, 0x8d, 0xdc, 0xcd, 0xd5, 0x1e, 0x41, 0xb6, 0xe7,
+	0x3f, 0x0d, 0x24, 0xf6, 0x8d, 0xda, 0xfd, 0xe5, 0x03, 0x29, 0x7f, 0x5b, 0xfe, 0x30, 0x11, 0x0d,
+	0xfc, 0xe4, 0x61, 0x44, 0xb9, 0xb8, 0x1c, 0xd4, 0xb6, 0xd3, 0x8e, 0x24, 0x9b, 0x3a, 0xe4, 0xe4,
+	0xe0, 0xa2, 0x0c, 0x97, 0xd8, 0x32, 0x6b, 0x0b, 0x35, 0xcb, 0x5a, 0xc3, 0x6d, 0x5e, 0xad, 0x07,
+	0x44, 0x6d, 0x12, 0x89, 0x65, 0x21, 0xe5, 0xc6, 0xcf, 0x01, 0xe6, 0xbb, 0x00, 0x79, 0x20, 0xce,
+	0x97, 0x96, 0x83, 0xb8, 0x69, 0x9e, 0x41, 0x4e, 0x9e, 0x2b, 0x91, 0x12, 0x63, 0x7f, 0xad, 0x9a,
+	0xc0, 0x49, 0x25, 0x9e, 0x03, 0xcc, 0x47, 0x5d, 0xf5, 0xc4, 0xd2, 0xec, 0x5b, 0x9b, 0x4f, 0x88,
+	0xf2, 0x4a, 0x8b, 0x2e, 0x5c, 0x69, 0xd1, 0x95, 0x57, 0x5e, 0x41, 0x31, 0x1a, 0x41, 0xc9, 0xdc,
+	0x2f, 0xf1, 0xd1, 0xb5, 0xb6, 0xb9, 0x88, 0x96, 0x02, 0xfe, 0x1a, 0x2a, 0xc9, 0xf1, 0x90, 0xd4,
+	0x62, 0xd6, 0x5e, 0x98, 0xd0, 0x6a, 0xc9, 0xe1, 0x48, 0x5e, 0x4f, 0x0e, 0x85, 0xea, 0x7a, 0xea,
+	0xa4, 0x98, 0x7a, 0x3d, 0x39, 0x0a, 0xaa, 0xeb, 0xa9, 0xf3, 0xe1, 0xf2, 0x75, 0x15, 0x69, 0x21,
+	0x26, 0x1e, 0x69, 0x8b, 0x43, 0x61, 0x6d, 0x3b, 0xed, 0x48, 0xda, 0xe0, 0x1b, 0xf1, 0xdb, 0xd1,
+	0x7c, 0xb4, 0x33, 0xc3, 0xa4, 0x5a, 0xec, 0x58, 0x29, 0x31, 0xf4, 0x46, 0x4e, 0xff, 0x11, 0x8a,
+	0x29, 0x0d, 0x52, 0xbb, 0x75, 0xcd, 0x4c, 0x3d, 0x93, 0x52, 0xfc, 0x0a, 0xca, 0x89, 0x4e, 0xab,
+	0x94, 0x49, 0xeb, 0xbe, 0x29, 0x72, 0x7c, 0x0b, 0x1b, 0x0b, 0x5d, 0x96, 0x7c, 0x26, 0x1d, 0x99,
+	0xda, 0x7b, 0x53, 0x38, 0xec, 0x8a, 0x1f, 0x54, 0x83, 0xb0, 0xa6, 0xc4, 0x3b, 0x71, 0xb2, 0xf0,
+	0x18, 0xd8, 0x80, 0x55, 0x75, 0x8a, 0xf5, 0xe2, 0x38, 0xd1, 0x13, 0xc8, 0xc9, 0x96, 0x1a, 0x25,
+	0x4a, 0xac, 0xbf, 0xc6, 0x09, 0x77, 0x21, 0x2b, 0xda, 0xaa, 0x7a, 0x34, 0xde, 0x62, 0xe3, 0x64,
+	0x4f, 0x21, 0xaf, 0x3a, 0xab, 0xca, 0xed, 0x64, 0x9f, 0x5d, 0x7a, 0x1a, 0xdb, 0xeb, 0x3c, 0x47,
+	0xe7, 0xbd, 0x36, 0x4e, 0xf8, 0x35, 0x64, 0x45, 0xc7, 0x52, 0x4f, 0xc7, 0xbb, 0x57, 0x6d, 0x3d,
+	0xde, 0x8f, 0xac, 0xb5, 0x67, 0xda, 0xfb, 0x9c, 0xf8, 0x9f, 0xc1, 0x8b, 0xff, 0x0c, 0x00, 0x35,
+	0x87, 0x74, 0x39, 0x47, 0x18, 0x00, 0x00,
 }
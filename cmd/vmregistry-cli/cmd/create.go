@@ -20,6 +20,8 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
 	"os"
 
 	"github.com/golang/glog"
@@ -35,6 +37,15 @@ var (
 	createVMCores       uint32
 	createVMSize        uint64
 	createVMSourceImage string
+	createVMNetwork     string
+	createVMLabels      []string
+	createVMFlavor      string
+	createVMTemplate    string
+	createVMSSHKeys     []string
+	createVMUserData    string
+	createVMHostname    string
+
+	requestID string
 )
 
 // createCmd represents the create command
@@ -50,6 +61,33 @@ to quickly create a Cobra application.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		createVMSize = createVMSize * 1024 * 1024 * 1024
 
+		// Only the sizing given explicitly overrides the flavor.
+		if createVMFlavor != "" {
+			if !cmd.Flags().Changed("mem") {
+				createVMMem = 0
+			}
+			if !cmd.Flags().Changed("cores") {
+				createVMCores = 0
+			}
+			if !cmd.Flags().Changed("size") {
+				createVMSize = 0
+			}
+		}
+
+		labels, err := parseLabels(createVMLabels)
+		if err != nil {
+			glog.Fatalf("%v", err)
+		}
+
+		userData := ""
+		if createVMUserData != "" {
+			b, err := ioutil.ReadFile(createVMUserData)
+			if err != nil {
+				glog.Fatalf("failed to read user data: %v", err)
+			}
+			userData = string(b)
+		}
+
 		initCredStoreSession()
 
 		ctx, err := vmregistryContext(context.Background())
@@ -62,21 +100,44 @@ to quickly create a Cobra application.`,
 			glog.Fatalf("failed to create a client: %v", err)
 		}
 
-		vm, err := client.Create(ctx, &pb.CreateRequest{
+		op, err := client.Create(ctx, &pb.CreateRequest{
 			Name:        createVMName,
 			Mem:         createVMMem,
 			Cores:       createVMCores,
 			Size:        createVMSize,
 			SourceImage: createVMSourceImage,
+			Network:     createVMNetwork,
+			RequestId:   requestID,
+			Labels:      labels,
+			Flavor:      createVMFlavor,
+			Template:    createVMTemplate,
+
+			SshAuthorizedKeys: createVMSSHKeys,
+			UserData:          userData,
+			Hostname:          createVMHostname,
 		})
 		if err != nil {
 			glog.Fatalf("failed to create VM: %v", err)
 		}
 
+		if operationAsync {
+			fmt.Println(op.Id)
+			return
+		}
+
+		op, err = waitForOperation(ctx, client, op)
+		if err != nil {
+			glog.Fatalf("failed to wait for VM creation: %v", err)
+		}
+		if op.Status != pb.Operation_DONE {
+			glog.Fatalf("failed to create VM: %s", op.Error)
+		}
+		vm := op.Vm
+
 		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Name", "IP"})
+		table.SetHeader([]string{"Name", "IP", "Network"})
 
-		table.Append([]string{vm.Name, vm.Ip})
+		table.Append([]string{vm.Name, vm.Ip, vm.Network})
 
 		table.Render()
 	},
@@ -99,4 +160,13 @@ func init() {
 	createCmd.Flags().Uint32Var(&createVMCores, "cores", 1, "vm cores")
 	createCmd.Flags().Uint64Var(&createVMSize, "size", 3, "vm disk in GB")
 	createCmd.Flags().StringVar(&createVMSourceImage, "source-image", "", "vm source image")
+	createCmd.Flags().StringVar(&createVMNetwork, "network", "", "vm network, server default if empty")
+	createCmd.Flags().StringVar(&createVMFlavor, "flavor", "", "vm flavor, see flavors; mem, cores and size override it when given")
+	createCmd.Flags().StringVar(&createVMTemplate, "template", "", "domain template, see templates; flavor or server default if empty")
+	createCmd.Flags().StringArrayVar(&createVMSSHKeys, "ssh-key", nil, "ssh public key authorized in the vm through cloud-init, can be repeated")
+	createCmd.Flags().StringVar(&createVMUserData, "user-data-file", "", "file with cloud-init user data, e.g. #cloud-config or a script")
+	createCmd.Flags().StringVar(&createVMHostname, "hostname", "", "vm host name set by cloud-init, defaults to the vm name")
+	createCmd.Flags().StringSliceVar(&createVMLabels, "label", nil, "vm label as key=value, can be repeated")
+	createCmd.Flags().StringVar(&requestID, "request-id", "", "unique id of this request, reruns with the same id don't create the VM twice")
+	createCmd.Flags().BoolVar(&operationAsync, "async", false, "print the operation id instead of waiting for the VM")
 }
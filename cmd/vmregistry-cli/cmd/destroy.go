@@ -20,6 +20,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
@@ -56,12 +57,26 @@ to quickly create a Cobra application.`,
 			glog.Fatalf("failed to create a client: %v", err)
 		}
 
-		_, err = client.Destroy(ctx, &pb.DestroyRequest{
-			Name: name,
+		op, err := client.Destroy(ctx, &pb.DestroyRequest{
+			Name:      name,
+			RequestId: requestID,
 		})
 		if err != nil {
 			glog.Fatalf("failed to destroy VM: %v", err)
 		}
+
+		if operationAsync {
+			fmt.Println(op.Id)
+			return
+		}
+
+		op, err = waitForOperation(ctx, client, op)
+		if err != nil {
+			glog.Fatalf("failed to wait for VM destruction: %v", err)
+		}
+		if op.Status != pb.Operation_DONE {
+			glog.Fatalf("failed to destroy VM: %s", op.Error)
+		}
 	},
 }
 
@@ -77,4 +92,6 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// destroyCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	destroyCmd.Flags().StringVar(&requestID, "request-id", "", "unique id of this request, reruns with the same id return the original result")
+	destroyCmd.Flags().BoolVar(&operationAsync, "async", false, "print the operation id instead of waiting for the VM to be gone")
 }
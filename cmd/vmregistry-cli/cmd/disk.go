@@ -0,0 +1,136 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+var (
+	attachDiskSize   uint64
+	attachDiskRetain bool
+)
+
+func printDisks(disks []*pb.Disk) {
+	if outputJSON {
+		b, _ := json.Marshal(disks)
+		fmt.Println(string(b))
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Size", "Target", "Retain", "Path", "Detaching"})
+
+	for _, d := range disks {
+		table.Append([]string{d.Name, fmt.Sprintf("%d GB", d.Size/1024/1024/1024), d.Target, fmt.Sprintf("%v", d.Retain), d.Path, fmt.Sprintf("%v", d.Detaching)})
+	}
+	table.Render()
+}
+
+type diskAction func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Disk, error)
+
+// newDiskCmd creates a command acting on the disks of a VM.
+func newDiskCmd(use string, short string, nargs int, action diskAction) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != nargs {
+				glog.Fatalf("usage: disk %s", use)
+			}
+
+			initCredStoreSession()
+
+			ctx, err := vmregistryContext(context.Background())
+			if err != nil {
+				glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+			}
+
+			client, err := newClient()
+			if err != nil {
+				glog.Fatalf("failed to create a client: %v", err)
+			}
+
+			disks, err := action(ctx, client, args)
+			if err != nil {
+				glog.Fatalf("failed to %s: %v", cmd.Name(), err)
+			}
+
+			printDisks(disks)
+		},
+	}
+}
+
+var diskCmd = &cobra.Command{
+	Use:   "disk",
+	Short: "Manage data disks of VMs",
+}
+
+var diskLsCmd = newDiskCmd("ls NAME", "List data disks of a VM", 1, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Disk, error) {
+	repl, err := client.ListDisks(ctx, &pb.ListDisksRequest{Name: args[0]})
+	if err != nil {
+		return nil, err
+	}
+	return repl.Disks, nil
+})
+
+var diskAttachCmd = newDiskCmd("attach NAME DISK", "Create a blank data disk and attach it to a VM", 2, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Disk, error) {
+	if attachDiskSize == 0 {
+		return nil, fmt.Errorf("--size not specified")
+	}
+	disk, err := client.AttachDisk(ctx, &pb.AttachDiskRequest{
+		Name:   args[0],
+		Disk:   args[1],
+		Size:   attachDiskSize * 1024 * 1024 * 1024,
+		Retain: attachDiskRetain,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.Disk{disk}, nil
+})
+
+var diskDetachCmd = newDiskCmd("detach NAME DISK", "Detach a data disk, removing it unless retained", 2, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Disk, error) {
+	disk, err := client.DetachDisk(ctx, &pb.DetachDiskRequest{
+		Name: args[0],
+		Disk: args[1],
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.Disk{disk}, nil
+})
+
+func init() {
+	RootCmd.AddCommand(diskCmd)
+	diskCmd.AddCommand(diskLsCmd, diskAttachCmd, diskDetachCmd)
+
+	diskCmd.PersistentFlags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+	diskAttachCmd.Flags().Uint64Var(&attachDiskSize, "size", 0, "disk size in GB")
+	diskAttachCmd.Flags().BoolVar(&attachDiskRetain, "retain", false, "keep the volume when the disk is detached or the VM destroyed")
+}
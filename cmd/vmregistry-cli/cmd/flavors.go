@@ -0,0 +1,76 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// flavorsCmd represents the flavors command
+var flavorsCmd = &cobra.Command{
+	Use:   "flavors",
+	Short: "List the VM flavors configured on the server",
+	Run: func(cmd *cobra.Command, args []string) {
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		repl, err := client.ListFlavors(ctx, &pb.ListFlavorsRequest{})
+		if err != nil {
+			glog.Fatalf("failed to get list of flavors: %v", err)
+		}
+
+		if outputJSON {
+			b, _ := json.Marshal(repl.Flavors)
+			fmt.Println(string(b))
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Memory", "Cores", "Disk", "Template"})
+
+		for _, f := range repl.Flavors {
+			table.Append([]string{f.Name, fmt.Sprintf("%d GB", f.Mem), fmt.Sprintf("%d", f.Cores), fmt.Sprintf("%d GB", f.Size/1024/1024/1024), f.Template})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(flavorsCmd)
+
+	flavorsCmd.Flags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+}
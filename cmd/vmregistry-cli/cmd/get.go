@@ -0,0 +1,102 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get NAME",
+	Short: "Show details of a single VM",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			glog.Fatalf("get needs a name")
+		}
+
+		name := args[0]
+
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		vm, err := client.Get(ctx, &pb.GetVMRequest{
+			Name: name,
+		})
+		if err != nil {
+			glog.Fatalf("failed to get VM: %v", err)
+		}
+
+		if outputJSON {
+			b, _ := json.Marshal(vm)
+			fmt.Println(string(b))
+			return
+		}
+
+		created := ""
+		if vm.Created != 0 {
+			created = time.Unix(vm.Created, 0).Format(time.RFC3339)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.AppendBulk([][]string{
+			{"Name", vm.Name},
+			{"State", vm.State.String()},
+			{"IP", vm.Ip},
+			{"IPv6", vm.Ipv6},
+			{"Network", vm.Network},
+			{"MAC", strings.Join(vm.Macs, ", ")},
+			{"Memory", fmt.Sprintf("%d GB", vm.Mem)},
+			{"Cores", fmt.Sprintf("%d", vm.Cores)},
+			{"Flavor", vm.Flavor},
+			{"Template", vm.Template},
+			{"Disk", fmt.Sprintf("%d GB", vm.Size/1024/1024/1024)},
+			{"Source image", vm.SourceImage},
+			{"Created", created},
+			{"Labels", formatLabels(vm.Labels)},
+		})
+		table.Render()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+}
@@ -0,0 +1,115 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// parseLabels parses a list of key=value pairs.
+func parseLabels(specs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", spec)
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels, nil
+}
+
+// formatLabels renders labels as sorted key=value pairs.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// labelCmd represents the label command
+var labelCmd = &cobra.Command{
+	Use:   "label NAME key=value... [key-...]",
+	Short: "Set or remove (key-) labels of a VM",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			glog.Fatalf("label needs a name and at least one label")
+		}
+
+		name := args[0]
+
+		set := []string{}
+		remove := []string{}
+		for _, arg := range args[1:] {
+			if strings.HasSuffix(arg, "-") && !strings.Contains(arg, "=") {
+				remove = append(remove, strings.TrimSuffix(arg, "-"))
+			} else {
+				set = append(set, arg)
+			}
+		}
+		labels, err := parseLabels(set)
+		if err != nil {
+			glog.Fatalf("%v", err)
+		}
+
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		vm, err := client.UpdateLabels(ctx, &pb.UpdateLabelsRequest{
+			Name:   name,
+			Set:    labels,
+			Remove: remove,
+		})
+		if err != nil {
+			glog.Fatalf("failed to update labels: %v", err)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Labels"})
+
+		table.Append([]string{vm.Name, formatLabels(vm.Labels)})
+
+		table.Render()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(labelCmd)
+}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/golang/glog"
 	"github.com/olekukonko/tablewriter"
@@ -33,6 +34,14 @@ import (
 
 var (
 	outputJSON bool
+
+	lsName          string
+	lsStates        []string
+	lsNetwork       string
+	lsLabelSelector string
+	lsOrderBy       string
+	lsPageSize      uint32
+	lsPageToken     string
 )
 
 // lsCmd represents the ls command
@@ -58,11 +67,33 @@ to quickly create a Cobra application.`,
 			glog.Fatalf("failed to create a client: %v", err)
 		}
 
-		repl, err := client.List(ctx, &pb.ListVMRequest{})
+		filter := &pb.ListFilter{
+			Name:          lsName,
+			Network:       lsNetwork,
+			LabelSelector: lsLabelSelector,
+		}
+		for _, state := range lsStates {
+			v, ok := pb.VM_State_value[strings.ToUpper(state)]
+			if !ok {
+				glog.Fatalf("unknown VM state %q", state)
+			}
+			filter.States = append(filter.States, pb.VM_State(v))
+		}
+
+		repl, err := client.List(ctx, &pb.ListVMRequest{
+			Filter:    filter,
+			OrderBy:   lsOrderBy,
+			PageSize:  lsPageSize,
+			PageToken: lsPageToken,
+		})
 		if err != nil {
 			glog.Fatalf("failed to get list of VMs: %v", err)
 		}
 
+		if repl.NextPageToken != "" {
+			fmt.Fprintf(os.Stderr, "next page: --page-token %s\n", repl.NextPageToken)
+		}
+
 		if outputJSON {
 			b, _ := json.Marshal(repl)
 			fmt.Println(string(b))
@@ -70,10 +101,10 @@ to quickly create a Cobra application.`,
 		}
 
 		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Name", "MAC", "IP"})
+		table.SetHeader([]string{"Name", "MAC", "IP", "State"})
 
 		for _, vm := range repl.Vms {
-			table.Append([]string{vm.Name, vm.Mac, vm.Ip})
+			table.Append([]string{vm.Name, vm.Mac, vm.Ip, vm.State.String()})
 		}
 		table.Render()
 	},
@@ -89,4 +120,11 @@ func init() {
 	// lsCmd.PersistentFlags().String("foo", "", "A help for foo")
 
 	lsCmd.Flags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+	lsCmd.Flags().StringVarP(&lsLabelSelector, "selector", "l", "", "only list VMs matching the label selector, e.g. owner=ops,!expiry")
+	lsCmd.Flags().StringVar(&lsName, "name", "", "only list VMs with names starting with this prefix, or matching this glob")
+	lsCmd.Flags().StringSliceVar(&lsStates, "state", nil, "only list VMs in this state, can be repeated")
+	lsCmd.Flags().StringVar(&lsNetwork, "network", "", "only list VMs on this network")
+	lsCmd.Flags().StringVar(&lsOrderBy, "order-by", "", "sort by name, created, mem, cores, size, state, network or ip, optionally followed by desc")
+	lsCmd.Flags().Uint32Var(&lsPageSize, "page-size", 0, "maximum number of VMs to list, 0 lists all of them")
+	lsCmd.Flags().StringVar(&lsPageToken, "page-token", "", "continue listing from a previous page")
 }
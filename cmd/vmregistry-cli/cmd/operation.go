@@ -0,0 +1,184 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+var (
+	operationAsync bool
+	operationsVM   string
+)
+
+// operationPollTimeout is the time a single WaitOperation call blocks for.
+const operationPollTimeout = 10
+
+// waitForOperation waits for the operation to finish, printing its steps to
+// stderr as they start.
+func waitForOperation(ctx context.Context, client pb.VMRegistryClient, op *pb.Operation) (*pb.Operation, error) {
+	printed := 0
+	for {
+		for ; printed < len(op.Steps); printed++ {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", op.VmName, op.Steps[printed].Name)
+		}
+
+		if op.Status != pb.Operation_PENDING && op.Status != pb.Operation_RUNNING {
+			return op, nil
+		}
+
+		var err error
+		op, err = client.WaitOperation(ctx, &pb.WaitOperationRequest{
+			Id:      op.Id,
+			Timeout: operationPollTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// operationTime formats an optional unix timestamp.
+func operationTime(t int64) string {
+	if t == 0 {
+		return ""
+	}
+	return time.Unix(t, 0).Format(time.RFC3339)
+}
+
+func printOperations(ops []*pb.Operation) {
+	if outputJSON {
+		b, _ := json.Marshal(ops)
+		fmt.Println(string(b))
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "Kind", "VM", "Status", "Step", "Created", "Finished", "Error"})
+
+	for _, op := range ops {
+		step := ""
+		if n := len(op.Steps); n > 0 {
+			step = op.Steps[n-1].Name
+		}
+		table.Append([]string{op.Id, op.Kind.String(), op.VmName, op.Status.String(), step, operationTime(op.Created), operationTime(op.Finished), op.Error})
+	}
+	table.Render()
+}
+
+type operationAction func(ctx context.Context, client pb.VMRegistryClient, id string) (*pb.Operation, error)
+
+// newOperationCmd creates a command acting on a single operation.
+func newOperationCmd(use string, short string, action operationAction) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " ID",
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				glog.Fatalf("%s needs an operation id", use)
+			}
+
+			initCredStoreSession()
+
+			ctx, err := vmregistryContext(context.Background())
+			if err != nil {
+				glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+			}
+
+			client, err := newClient()
+			if err != nil {
+				glog.Fatalf("failed to create a client: %v", err)
+			}
+
+			op, err := action(ctx, client, args[0])
+			if err != nil {
+				glog.Fatalf("failed to %s operation: %v", use, err)
+			}
+
+			printOperations([]*pb.Operation{op})
+		},
+	}
+}
+
+var operationCmd = &cobra.Command{
+	Use:     "operation",
+	Aliases: []string{"op"},
+	Short:   "Inspect create and destroy operations",
+}
+
+var operationLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List recent operations",
+	Run: func(cmd *cobra.Command, args []string) {
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		repl, err := client.ListOperations(ctx, &pb.ListOperationsRequest{
+			VmName: operationsVM,
+		})
+		if err != nil {
+			glog.Fatalf("failed to list operations: %v", err)
+		}
+
+		printOperations(repl.Operations)
+	},
+}
+
+var operationGetCmd = newOperationCmd("get", "Show an operation", func(ctx context.Context, client pb.VMRegistryClient, id string) (*pb.Operation, error) {
+	return client.GetOperation(ctx, &pb.GetOperationRequest{Id: id})
+})
+
+var operationWaitCmd = newOperationCmd("wait", "Wait for an operation to finish", func(ctx context.Context, client pb.VMRegistryClient, id string) (*pb.Operation, error) {
+	op, err := client.GetOperation(ctx, &pb.GetOperationRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return waitForOperation(ctx, client, op)
+})
+
+var operationCancelCmd = newOperationCmd("cancel", "Cancel a running create, or a destroy that hasn't started its first step", func(ctx context.Context, client pb.VMRegistryClient, id string) (*pb.Operation, error) {
+	return client.CancelOperation(ctx, &pb.CancelOperationRequest{Id: id})
+})
+
+func init() {
+	RootCmd.AddCommand(operationCmd)
+	operationCmd.AddCommand(operationLsCmd, operationGetCmd, operationWaitCmd, operationCancelCmd)
+
+	operationCmd.PersistentFlags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+	operationLsCmd.Flags().StringVar(&operationsVM, "vm", "", "only show operations on this VM")
+}
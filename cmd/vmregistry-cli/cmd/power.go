@@ -0,0 +1,111 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+var (
+	stopVMTimeout uint32
+	stopVMForce   bool
+)
+
+type powerAction func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error)
+
+// newPowerCmd creates a command that changes the power state of a single VM.
+func newPowerCmd(use string, short string, action powerAction) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " NAME",
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				glog.Fatalf("%s needs a name", use)
+			}
+
+			name := args[0]
+
+			initCredStoreSession()
+
+			ctx, err := vmregistryContext(context.Background())
+			if err != nil {
+				glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+			}
+
+			client, err := newClient()
+			if err != nil {
+				glog.Fatalf("failed to create a client: %v", err)
+			}
+
+			vm, err := action(ctx, client, name)
+			if err != nil {
+				glog.Fatalf("failed to %s VM: %v", use, err)
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Name", "State"})
+
+			table.Append([]string{vm.Name, vm.State.String()})
+
+			table.Render()
+		},
+	}
+}
+
+var startCmd = newPowerCmd("start", "Start a VM", func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error) {
+	return client.Start(ctx, &pb.StartRequest{Name: name})
+})
+
+var stopCmd = newPowerCmd("stop", "Shut down a VM, stopping it forcefully on timeout", func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error) {
+	return client.Stop(ctx, &pb.StopRequest{
+		Name:    name,
+		Timeout: stopVMTimeout,
+		Force:   stopVMForce,
+	})
+})
+
+var rebootCmd = newPowerCmd("reboot", "Ask a VM to reboot", func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error) {
+	return client.Reboot(ctx, &pb.RebootRequest{Name: name})
+})
+
+var resetCmd = newPowerCmd("reset", "Hard reset a VM", func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error) {
+	return client.Reset(ctx, &pb.ResetRequest{Name: name})
+})
+
+var suspendCmd = newPowerCmd("suspend", "Pause a running VM", func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error) {
+	return client.Suspend(ctx, &pb.SuspendRequest{Name: name})
+})
+
+var resumeCmd = newPowerCmd("resume", "Resume a paused VM", func(ctx context.Context, client pb.VMRegistryClient, name string) (*pb.VM, error) {
+	return client.Resume(ctx, &pb.ResumeRequest{Name: name})
+})
+
+func init() {
+	RootCmd.AddCommand(startCmd, stopCmd, rebootCmd, resetCmd, suspendCmd, resumeCmd)
+
+	stopCmd.Flags().Uint32Var(&stopVMTimeout, "timeout", 0, "seconds to wait for a graceful shutdown, 0 for server default")
+	stopCmd.Flags().BoolVar(&stopVMForce, "force", false, "stop the VM immediately")
+}
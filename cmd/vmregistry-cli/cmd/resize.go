@@ -0,0 +1,138 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+var (
+	resizeDiskSize  uint64
+	resizeDiskForce bool
+
+	resizeMem   uint64
+	resizeCores uint32
+)
+
+// resizeCmd represents the resize command
+var resizeCmd = &cobra.Command{
+	Use:   "resize NAME",
+	Short: "Change the memory and cores of a VM, live if possible",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			glog.Fatalf("resize needs a name")
+		}
+		if resizeMem == 0 && resizeCores == 0 {
+			glog.Fatalf("resize needs --mem or --cores")
+		}
+
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		repl, err := client.Resize(ctx, &pb.ResizeRequest{
+			Name:  args[0],
+			Mem:   resizeMem,
+			Cores: resizeCores,
+		})
+		if err != nil {
+			glog.Fatalf("failed to resize VM: %v", err)
+		}
+		vm := repl.Vm
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Memory", "Cores"})
+
+		table.Append([]string{vm.Name, fmt.Sprintf("%d GB", vm.Mem), fmt.Sprintf("%d", vm.Cores)})
+
+		table.Render()
+
+		if repl.RebootRequired {
+			fmt.Fprintf(os.Stderr, "%s needs a reboot for the new size to fully apply\n", vm.Name)
+		}
+	},
+}
+
+// resizeDiskCmd represents the resize-disk command
+var resizeDiskCmd = &cobra.Command{
+	Use:   "resize-disk NAME",
+	Short: "Grow the disk of a VM, or shrink it with --force",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			glog.Fatalf("resize-disk needs a name")
+		}
+		if resizeDiskSize == 0 {
+			glog.Fatalf("resize-disk needs --size")
+		}
+
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		vm, err := client.ResizeDisk(ctx, &pb.ResizeDiskRequest{
+			Name:  args[0],
+			Size:  resizeDiskSize * 1024 * 1024 * 1024,
+			Force: resizeDiskForce,
+		})
+		if err != nil {
+			glog.Fatalf("failed to resize disk: %v", err)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Disk"})
+
+		table.Append([]string{vm.Name, fmt.Sprintf("%d GB", vm.Size/1024/1024/1024)})
+
+		table.Render()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(resizeCmd, resizeDiskCmd)
+
+	resizeCmd.Flags().Uint64Var(&resizeMem, "mem", 0, "new vm memory in GB, unchanged if 0")
+	resizeCmd.Flags().Uint32Var(&resizeCores, "cores", 0, "new vm cores, unchanged if 0")
+
+	resizeDiskCmd.Flags().Uint64Var(&resizeDiskSize, "size", 0, "new disk size in GB")
+	resizeDiskCmd.Flags().BoolVar(&resizeDiskForce, "force", false, "allow shrinking the disk of a shut off VM, possibly losing data")
+}
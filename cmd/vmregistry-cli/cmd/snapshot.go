@@ -0,0 +1,127 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+func printSnapshots(snapshots []*pb.Snapshot) {
+	if outputJSON {
+		b, _ := json.Marshal(snapshots)
+		fmt.Println(string(b))
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Created", "Disks", "Quiesced"})
+
+	for _, s := range snapshots {
+		table.Append([]string{s.Name, operationTime(s.Created), strings.Join(s.Disks, ","), fmt.Sprintf("%v", s.Quiesced)})
+	}
+	table.Render()
+}
+
+type snapshotAction func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Snapshot, error)
+
+// newSnapshotCmd creates a command acting on the snapshots of a VM.
+func newSnapshotCmd(use string, short string, nargs int, action snapshotAction) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != nargs {
+				glog.Fatalf("usage: snapshot %s", use)
+			}
+
+			initCredStoreSession()
+
+			ctx, err := vmregistryContext(context.Background())
+			if err != nil {
+				glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+			}
+
+			client, err := newClient()
+			if err != nil {
+				glog.Fatalf("failed to create a client: %v", err)
+			}
+
+			snapshots, err := action(ctx, client, args)
+			if err != nil {
+				glog.Fatalf("failed to %s: %v", cmd.Name(), err)
+			}
+
+			printSnapshots(snapshots)
+		},
+	}
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage snapshots of VMs",
+}
+
+var snapshotLsCmd = newSnapshotCmd("ls NAME", "List snapshots of a VM", 1, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Snapshot, error) {
+	repl, err := client.ListSnapshots(ctx, &pb.ListSnapshotsRequest{Name: args[0]})
+	if err != nil {
+		return nil, err
+	}
+	return repl.Snapshots, nil
+})
+
+var snapshotCreateCmd = newSnapshotCmd("create NAME SNAPSHOT", "Snapshot the disks of a VM", 2, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Snapshot, error) {
+	snap, err := client.CreateSnapshot(ctx, &pb.CreateSnapshotRequest{Name: args[0], Snapshot: args[1]})
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.Snapshot{snap}, nil
+})
+
+var snapshotRevertCmd = newSnapshotCmd("revert NAME SNAPSHOT", "Revert the disks of a shut off VM to a snapshot", 2, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Snapshot, error) {
+	snap, err := client.RevertSnapshot(ctx, &pb.RevertSnapshotRequest{Name: args[0], Snapshot: args[1]})
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.Snapshot{snap}, nil
+})
+
+var snapshotRmCmd = newSnapshotCmd("rm NAME SNAPSHOT", "Delete a snapshot", 2, func(ctx context.Context, client pb.VMRegistryClient, args []string) ([]*pb.Snapshot, error) {
+	snap, err := client.DeleteSnapshot(ctx, &pb.DeleteSnapshotRequest{Name: args[0], Snapshot: args[1]})
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.Snapshot{snap}, nil
+})
+
+func init() {
+	RootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotLsCmd, snapshotCreateCmd, snapshotRevertCmd, snapshotRmCmd)
+
+	snapshotCmd.PersistentFlags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+}
@@ -0,0 +1,95 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch [NAME]",
+	Short: "Print VM lifecycle events as they happen",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			glog.Fatalf("watch takes at most one name")
+		}
+
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		initCredStoreSession()
+
+		ctx, err := vmregistryContext(context.Background())
+		if err != nil {
+			glog.Fatalf("failed to acquire a client vmregistry context: %v", err)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			glog.Fatalf("failed to create a client: %v", err)
+		}
+
+		stream, err := client.Watch(ctx, &pb.WatchRequest{
+			Name: name,
+		})
+		if err != nil {
+			glog.Fatalf("failed to watch VMs: %v", err)
+		}
+
+		for {
+			ev, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				glog.Fatalf("failed to receive event: %v", err)
+			}
+
+			if outputJSON {
+				b, _ := json.Marshal(ev)
+				fmt.Println(string(b))
+				continue
+			}
+
+			state, ip := "", ""
+			if ev.Vm != nil {
+				state, ip = ev.Vm.State.String(), ev.Vm.Ip
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\n", time.Unix(ev.Time, 0).Format(time.RFC3339), ev.Type, ev.Name, state, ip)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().BoolVar(&outputJSON, "json", false, "Output in JSON")
+}
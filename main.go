@@ -21,15 +21,17 @@ package main
 import (
 	"context"
 	"flag"
-	"html/template"
-	"io/ioutil"
-	"net"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	pb "github.com/google/vmregistry/api"
 	"github.com/google/vmregistry/server"
 	"github.com/google/vmregistry/web"
 
 	"github.com/golang/glog"
+	"github.com/google/credstore/client"
 	"github.com/google/go-microservice-helpers/server"
 	"github.com/google/go-microservice-helpers/tracing"
 	"github.com/libvirt/libvirt-go"
@@ -37,22 +39,266 @@ import (
 
 var (
 	libvirtURI = flag.String("libvirt-uri", "", "libvirt connection uri")
-	vmTemplate = flag.String("vm-template-file", "", "path to libvirt xml template file to be used for vm creation")
-	vmNet      = flag.String("vm-net", "", "A subnet for VM ip address generation")
+	vmTemplate = flag.String("vm-template-file", "", "path to libvirt xml template file to be used for vm creation, named \"default\"")
+	vmTplDir   = flag.String("vm-template-dir", "", "directory of named libvirt xml templates, NAME.xml each")
+	vmDefTpl   = flag.String("vm-default-template", "", "template used when create request doesn't specify one, defaults to \"default\" or the first found")
+	vmNet      = flag.String("vm-net", "", "A subnet for VM ip address generation, configures the network named \"default\"")
+	vmReserved = flag.String("vm-net-reserved", "", "comma-separated ip addresses and ranges (a-b) of vm-net never given to VMs, e.g. the gateway")
+	vmNetworks = flag.String("vm-networks-file", "", "path to json file with a list of named vm networks")
+	vmDefNet   = flag.String("vm-default-network", "", "network used when create request doesn't specify one, defaults to the first configured")
+	ipamState  = flag.String("ipam-state-dir", "", "directory to persist ip allocations in")
+	ipamFile   = flag.String("ipam-state-file", "", "deprecated: allocations file of a single network setup, moved to default.json in -ipam-state-dir on startup")
 	vmVG       = flag.String("vm-vg", "", "lvm volume group for storage")
+	vmFlavors  = flag.String("vm-flavors-file", "", "path to json file with a list of named vm flavors")
+
+	storageBackend  = flag.String("storage-backend", "lvm", "vm disk storage: lvm (through lvmd), file (local qcow2 files) or zfs (zvol clones)")
+	lvmBinary       = flag.String("lvm-binary", "lvm", "lvm command, for what lvm storage can't do through lvmd")
+	fileStorageDir  = flag.String("file-storage-dir", "", "directory to keep vm disks in, for file storage")
+	fileStorageImgs = flag.String("file-storage-images-dir", "", "directory of source images, for file storage")
+	fileStorageMode = flag.String("file-storage-mode", "overlay", "how file storage creates disks from source images: overlay or copy")
+	qemuImgBinary   = flag.String("qemu-img-binary", "qemu-img", "qemu-img command, for file storage")
+	zfsDataset      = flag.String("zfs-dataset", "", "parent dataset of vm zvols, for zfs storage")
+	zfsImages       = flag.String("zfs-images-dataset", "", "parent dataset of source image zvols, for zfs storage")
+	zfsBinary       = flag.String("zfs-binary", "zfs", "zfs command, for zfs storage")
+
+	seedDir     = flag.String("cloud-init-seed-dir", "", "directory to write cloud-init seed images of VMs to, cloud-init is disabled if empty")
+	seedISOTool = flag.String("cloud-init-iso-tool", "genisoimage", "command building the seed iso images, genisoimage or mkisofs")
+
+	inventoryResync = flag.Duration("inventory-resync-interval", 5*time.Minute, "how often to reload all VMs from libvirt, on top of domain events")
 
 	lvmdAddress = flag.String("lvmd-address", "", "lvmd grpc address")
 	lvmdCA      = flag.String("lvmd-ca", "", "lvmd server ca")
 
-	dnsAPIURL = flag.String("pdns-api-url", "", "PowerDNS base URL")
-	dnsZone   = flag.String("pdns-zone", "", "Zone to host VMs")
-	dnsAPIKey = flag.String("pdns-api-key", "", "PowerDNS API Key")
+	dnsProvider = flag.String("dns-provider", "powerdns", "dns backend to publish VM records with: powerdns or rfc2136")
+
+	dnsAPIURL  = flag.String("pdns-api-url", "", "PowerDNS base URL")
+	dnsZone    = flag.String("pdns-zone", "", "Zone to host VMs")
+	dnsRevZone = flag.String("pdns-reverse-zone", "", "in-addr.arpa zone for ptr records of vm-net, none if empty")
+	dnsAPIKey  = flag.String("pdns-api-key", "", "PowerDNS API Key")
+
+	dnsStateFile         = flag.String("dns-state-file", "", "file to track the record sets published for VMs in, only those are removed by the reconciler")
+	dnsReconcileInterval = flag.Duration("dns-reconcile-interval", 0, "how often to check dns records against the VMs, 0 to disable")
+	dnsReconcileDryRun   = flag.Bool("dns-reconcile-dry-run", false, "only report dns drift, don't fix it")
+
+	rfc2136Server     = flag.String("rfc2136-server", "", "host:port of the dns server accepting dynamic updates")
+	rfc2136TSIGKey    = flag.String("rfc2136-tsig-key", "", "TSIG key name, updates are not signed if empty")
+	rfc2136TSIGSecret = flag.String("rfc2136-tsig-secret", "", "base64 TSIG secret")
+	rfc2136TSIGAlgo   = flag.String("rfc2136-tsig-algorithm", "hmac-sha256", "TSIG algorithm")
 )
 
+// newDNSProvider creates the dns backend selected with -dns-provider.
+func newDNSProvider() (server.DNSProvider, error) {
+	switch *dnsProvider {
+	case "powerdns":
+		return server.NewPowerDNSProvider(*dnsAPIURL, *dnsAPIKey), nil
+	case "rfc2136":
+		return server.NewRFC2136Provider(*rfc2136Server, *rfc2136TSIGKey, *rfc2136TSIGSecret, *rfc2136TSIGAlgo)
+	default:
+		return nil, fmt.Errorf("unknown dns provider %q", *dnsProvider)
+	}
+}
+
+// newTrackedDNSProvider creates the dns backend, tracking the published
+// records if -dns-state-file is set.
+func newTrackedDNSProvider() (server.DNSProvider, error) {
+	provider, err := newDNSProvider()
+	if err != nil || *dnsStateFile == "" {
+		return provider, err
+	}
+	return server.NewDNSLedger(provider, *dnsStateFile)
+}
+
+// newStorage creates the vm storage selected with -storage-backend. Only lvm
+// needs credstore, for the lvmd token.
+func newStorage(credstoreClient *client.CredstoreClient) (server.StorageManager, error) {
+	switch *storageBackend {
+	case "lvm":
+		if credstoreClient == nil {
+			return nil, fmt.Errorf("failed to init credstore, lvm storage needs it for lvmd")
+		}
+		lvmSessionTok, err := credstoreClient.GetTokenForRemote(context.Background(), *lvmdAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lvmd token: %v", err)
+		}
+		return server.NewLVMStorage(*lvmdAddress, *lvmdCA, *vmVG, lvmSessionTok, *lvmBinary, server.ExecRunner{})
+	case "file":
+		if *fileStorageDir == "" || *fileStorageImgs == "" {
+			return nil, fmt.Errorf("file storage needs -file-storage-dir and -file-storage-images-dir")
+		}
+		return server.NewFileStorage(*fileStorageDir, *fileStorageImgs, *fileStorageMode, *qemuImgBinary, server.ExecRunner{})
+	case "zfs":
+		if *zfsDataset == "" || *zfsImages == "" {
+			return nil, fmt.Errorf("zfs storage needs -zfs-dataset and -zfs-images-dataset")
+		}
+		return server.NewZFSStorage(*zfsDataset, *zfsImages, *zfsBinary, server.ExecRunner{})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", *storageBackend)
+	}
+}
+
+// loadTemplates loads the domain templates, the default one goes first.
+func loadTemplates() ([]*server.DomainTemplate, error) {
+	templates := []*server.DomainTemplate{}
+	if *vmTemplate != "" {
+		t, err := server.LoadDomainTemplate("default", *vmTemplate)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	if *vmTplDir != "" {
+		dirTemplates, err := server.LoadDomainTemplates(*vmTplDir)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, dirTemplates...)
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("neither -vm-template-file nor -vm-template-dir specified")
+	}
+
+	defaultName := *vmDefTpl
+	if defaultName == "" {
+		defaultName = "default"
+	}
+
+	ordered := []*server.DomainTemplate{}
+	seen := map[string]bool{}
+	for _, t := range templates {
+		if seen[t.Name] {
+			return nil, fmt.Errorf("duplicate template %s", t.Name)
+		}
+		seen[t.Name] = true
+
+		if t.Name == defaultName {
+			ordered = append([]*server.DomainTemplate{t}, ordered...)
+		} else {
+			ordered = append(ordered, t)
+		}
+	}
+
+	if *vmDefTpl != "" && ordered[0].Name != *vmDefTpl {
+		return nil, fmt.Errorf("default template %s is not configured", *vmDefTpl)
+	}
+
+	return ordered, nil
+}
+
+// loadFlavors loads the vm flavors, checking that their templates exist.
+func loadFlavors(templates []*server.DomainTemplate) ([]*server.Flavor, error) {
+	if *vmFlavors == "" {
+		return nil, nil
+	}
+
+	flavors, err := server.LoadFlavors(*vmFlavors)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, t := range templates {
+		known[t.Name] = true
+	}
+	for _, f := range flavors {
+		if f.Template != "" && !known[f.Template] {
+			return nil, fmt.Errorf("flavor %s uses unknown template %s", f.Name, f.Template)
+		}
+	}
+	return flavors, nil
+}
+
+// migrateIPAMStateFile moves the allocations kept in -ipam-state-file by
+// single network setups to the state of the "default" network.
+func migrateIPAMStateFile() error {
+	if *ipamFile == "" {
+		return nil
+	}
+	if *ipamState == "" {
+		return fmt.Errorf("-ipam-state-file is deprecated, set -ipam-state-dir")
+	}
+
+	dest := filepath.Join(*ipamState, "default.json")
+	if _, err := os.Stat(dest); err == nil {
+		glog.Warningf("ignoring -ipam-state-file, %s already exists", dest)
+		return nil
+	}
+
+	glog.Warningf("-ipam-state-file is deprecated, moving %s to %s", *ipamFile, dest)
+	if err := os.MkdirAll(*ipamState, 0755); err != nil {
+		return err
+	}
+	return os.Rename(*ipamFile, dest)
+}
+
+// loadNetworks configures the vm networks, the default one goes first.
+func loadNetworks() ([]*server.Network, error) {
+	configs := []server.NetworkConfig{}
+	if *vmNet != "" {
+		configs = append(configs, server.NetworkConfig{
+			Name:        "default",
+			CIDR:        *vmNet,
+			Reserved:    *vmReserved,
+			ReverseZone: *dnsRevZone,
+		})
+	}
+	if *vmNetworks != "" {
+		fileConfigs, err := server.LoadNetworkConfigs(*vmNetworks)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, fileConfigs...)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("neither -vm-net nor -vm-networks-file specified")
+	}
+
+	networks := []*server.Network{}
+	seen := map[string]bool{}
+	for _, cfg := range configs {
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("duplicate network %s", cfg.Name)
+		}
+		seen[cfg.Name] = true
+
+		if cfg.DNSZone == "" {
+			cfg.DNSZone = *dnsZone
+		}
+
+		n, err := server.NewNetwork(cfg, *ipamState)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Name == *vmDefNet {
+			networks = append([]*server.Network{n}, networks...)
+		} else {
+			networks = append(networks, n)
+		}
+	}
+
+	if *vmDefNet != "" && networks[0].Name != *vmDefNet {
+		return nil, fmt.Errorf("default network %s is not configured", *vmDefNet)
+	}
+
+	return networks, nil
+}
+
 func main() {
 	flag.Parse()
 	defer glog.Flush()
 
+	err := libvirt.EventRegisterDefaultImpl()
+	if err != nil {
+		glog.Fatalf("failed to register libvirt event loop: %v", err)
+	}
+	go func() {
+		for {
+			if err := libvirt.EventRunDefaultImpl(); err != nil {
+				glog.Errorf("libvirt event loop failed: %v", err)
+			}
+		}
+	}()
+
 	conn, err := libvirt.NewConnect(*libvirtURI)
 	if err != nil {
 		glog.Fatalf("failed to connect to libvirt: %v", err)
@@ -63,38 +309,72 @@ func main() {
 		glog.Fatalf("failed to init tracing interface: %v", err)
 	}
 
-	_, net, err := net.ParseCIDR(*vmNet)
+	err = migrateIPAMStateFile()
 	if err != nil {
-		glog.Fatalf("failed to parse vm net: %v", err)
+		glog.Fatalf("failed to migrate ipam state: %v", err)
+	}
+
+	networks, err := loadNetworks()
+	if err != nil {
+		glog.Fatalf("failed to configure vm networks: %v", err)
 	}
 
 	grpcServer, credstoreClient, err := serverhelpers.NewServer()
 	if err != nil {
 		glog.Fatalf("failed to init GRPC server: %v", err)
 	}
-	if credstoreClient == nil {
-		glog.Fatalf("failed to init credstore")
+
+	storage, err := newStorage(credstoreClient)
+	if err != nil {
+		glog.Fatalf("failed to configure vm storage: %v", err)
+	}
+
+	var seeds server.SeedManager
+	if *seedDir != "" {
+		seeds, err = server.NewISOSeeder(*seedDir, *seedISOTool, server.ExecRunner{})
+		if err != nil {
+			glog.Fatalf("failed to configure cloud-init seeds: %v", err)
+		}
 	}
 
-	lvmSessionTok, err := credstoreClient.GetTokenForRemote(context.Background(), *lvmdAddress)
+	templates, err := loadTemplates()
 	if err != nil {
-		glog.Fatalf("failed to get lvmd token: %v", err)
+		glog.Fatalf("failed to load vm templates: %v", err)
 	}
 
-	storage, err := server.NewLVMStorage(*lvmdAddress, *lvmdCA, *vmVG, lvmSessionTok)
+	flavors, err := loadFlavors(templates)
 	if err != nil {
-		glog.Fatalf("failed to create connection to lvmd: %v", err)
+		glog.Fatalf("failed to load vm flavors: %v", err)
 	}
 
-	tpl, err := ioutil.ReadFile(*vmTemplate)
+	dns, err := newTrackedDNSProvider()
 	if err != nil {
-		glog.Fatalf("failed to load vm template: %v", err)
+		glog.Fatalf("failed to configure dns: %v", err)
 	}
-	var xmlTemplate = template.Must(template.New("domain").Parse(string(tpl)))
 
-	dnsCli := server.NewDNSClient(*dnsAPIURL, *dnsZone, *dnsAPIKey)
+	svr := server.NewServer(conn, storage, seeds, networks, flavors, templates, dns)
 
-	svr := server.NewServer(conn, storage, net, dnsCli, xmlTemplate)
+	err = svr.SyncIPAllocations(context.Background())
+	if err != nil {
+		glog.Fatalf("failed to sync ip allocations: %v", err)
+	}
+
+	err = svr.WatchDomainEvents()
+	if err != nil {
+		glog.Fatalf("failed to subscribe to libvirt events: %v", err)
+	}
+
+	err = svr.SyncInventory(context.Background())
+	if err != nil {
+		glog.Fatalf("failed to load vm inventory: %v", err)
+	}
+	if *inventoryResync > 0 {
+		go svr.RunInventoryResync(context.Background(), *inventoryResync)
+	}
+
+	if *dnsReconcileInterval > 0 {
+		go svr.RunDNSReconciler(context.Background(), *dnsReconcileInterval, *dnsReconcileDryRun)
+	}
 
 	pb.RegisterVMRegistryServer(grpcServer, &svr)
 
@@ -65,6 +65,7 @@ type Zone struct {
 		Disabled bool   `json:"disabled"`
 		Content  string `json:"content"`
 	} `json:"records"`
+	RRsets []RRset `json:"rrsets"`
 }
 
 // Record struct
@@ -130,6 +131,22 @@ func New(baseURL string, vhost string, domain string, apikey string) *PowerDNS {
 	}
 }
 
+// GetZone returns the zone with all of its rrsets.
+func (p *PowerDNS) GetZone() (*Zone, error) {
+
+	error := new(Error)
+	zone := new(Zone)
+
+	resp, err := p.getSling().Get(p.domain).Receive(zone, error)
+
+	if err == nil && resp.StatusCode >= 400 {
+		error.Message = strings.Join([]string{resp.Status, error.Message}, " ")
+		return nil, error
+	}
+
+	return zone, err
+}
+
 // AddRecord ...
 func (p *PowerDNS) AddRecord(name string, recordType string, ttl int, content []string) (*Zone, error) {
 
@@ -0,0 +1,247 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// SeedManager builds the cloud-init seed images new VMs boot with.
+type SeedManager interface {
+	// CreateSeed writes the files to a seed image of the VM and returns the
+	// path to attach to the domain.
+	CreateSeed(ctx context.Context, name string, files map[string][]byte) (string, error)
+	RemoveSeed(ctx context.Context, name string) error
+}
+
+const (
+	maxUserDataSize = 64 << 10
+	maxHostnameSize = 253
+)
+
+var hostnameRe = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+// wantsCloudInit tells if the VM needs a seed.
+func wantsCloudInit(in *pb.CreateRequest) bool {
+	return len(in.GetSshAuthorizedKeys()) > 0 || in.GetUserData() != "" || in.GetHostname() != ""
+}
+
+func validateCloudInit(in *pb.CreateRequest) error {
+	if h := in.GetHostname(); h != "" && (len(h) > maxHostnameSize || !hostnameRe.MatchString(h)) {
+		return fmt.Errorf("invalid hostname %q", h)
+	}
+	for _, key := range in.GetSshAuthorizedKeys() {
+		if strings.TrimSpace(key) == "" || strings.ContainsAny(key, "\r\n") {
+			return fmt.Errorf("ssh authorized keys must be single lines")
+		}
+	}
+	if len(in.GetUserData()) > maxUserDataSize {
+		return fmt.Errorf("user data is larger than %d bytes", maxUserDataSize)
+	}
+	return nil
+}
+
+// cloudInitSeed is the content of a NoCloud seed.
+type cloudInitSeed struct {
+	InstanceID        string
+	Hostname          string
+	SSHAuthorizedKeys []string
+	UserData          string
+
+	MAC      string
+	IP       string
+	Prefix   int
+	Gateway  string
+	IPv6     string
+	Prefix6  int
+	Gateway6 string
+}
+
+type cloudInitMetaData struct {
+	InstanceID    string   `json:"instance-id"`
+	LocalHostname string   `json:"local-hostname"`
+	PublicKeys    []string `json:"public-keys,omitempty"`
+}
+
+type cloudInitEthernet struct {
+	Match     map[string]string `json:"match"`
+	Addresses []string          `json:"addresses"`
+	Gateway4  string            `json:"gateway4,omitempty"`
+	Gateway6  string            `json:"gateway6,omitempty"`
+}
+
+type cloudInitNetworkConfig struct {
+	Version   int                          `json:"version"`
+	Ethernets map[string]cloudInitEthernet `json:"ethernets"`
+}
+
+// files renders the meta-data, network-config and user-data files of the
+// seed. The first two are written as json, which is valid yaml.
+func (c cloudInitSeed) files() (map[string][]byte, error) {
+	metaData, err := json.Marshal(cloudInitMetaData{
+		InstanceID:    c.InstanceID,
+		LocalHostname: c.Hostname,
+		PublicKeys:    c.SSHAuthorizedKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eth := cloudInitEthernet{
+		Match:     map[string]string{"macaddress": c.MAC},
+		Addresses: []string{fmt.Sprintf("%s/%d", c.IP, c.Prefix)},
+		Gateway4:  c.Gateway,
+	}
+	if c.IPv6 != "" {
+		eth.Addresses = append(eth.Addresses, fmt.Sprintf("%s/%d", c.IPv6, c.Prefix6))
+		eth.Gateway6 = c.Gateway6
+	}
+	networkConfig, err := json.Marshal(cloudInitNetworkConfig{
+		Version:   2,
+		Ethernets: map[string]cloudInitEthernet{"primary": eth},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userData := c.UserData
+	if userData == "" {
+		userData = "#cloud-config\n"
+	}
+
+	return map[string][]byte{
+		"meta-data":      metaData,
+		"network-config": networkConfig,
+		"user-data":      []byte(userData),
+	}, nil
+}
+
+// ISOSeeder writes seeds as iso images labeled cidata, built with
+// genisoimage or a compatible tool.
+type ISOSeeder struct {
+	dir    string
+	tool   string
+	runner CommandRunner
+}
+
+// NewISOSeeder creates a seeder keeping the images in dir.
+func NewISOSeeder(dir string, tool string, runner CommandRunner) (*ISOSeeder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ISOSeeder{dir: dir, tool: tool, runner: runner}, nil
+}
+
+func (s *ISOSeeder) seedPath(name string) string {
+	return filepath.Join(s.dir, name+"-seed.iso")
+}
+
+// CreateSeed builds the iso image of the VM, replacing any previous one. The
+// image is only readable by its owner.
+func (s *ISOSeeder) CreateSeed(ctx context.Context, name string, files map[string][]byte) (string, error) {
+	tmp, err := ioutil.TempDir(s.dir, name+"-seed")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	out := filepath.Join(tmp, "seed.iso")
+	args := []string{"-output", out, "-volid", "cidata", "-joliet", "-rock"}
+	for _, fileName := range fileNames {
+		p := filepath.Join(tmp, fileName)
+		if err := ioutil.WriteFile(p, files[fileName], 0600); err != nil {
+			return "", err
+		}
+		args = append(args, p)
+	}
+
+	if _, err := s.runner.Run(ctx, s.tool, args...); err != nil {
+		return "", err
+	}
+
+	// user-data can carry passwords and keys. libvirt hands the image over
+	// to qemu when the VM starts.
+	if err := os.Chmod(out, 0600); err != nil {
+		return "", err
+	}
+
+	path := s.seedPath(name)
+	if err := os.Rename(out, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// RemoveSeed deletes the iso image of the VM, if there is one.
+func (s *ISOSeeder) RemoveSeed(ctx context.Context, name string) error {
+	err := os.Remove(s.seedPath(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// createSeed builds the seed of a new VM with its network configuration.
+func (s Server) createSeed(ctx context.Context, in *pb.CreateRequest, network *Network, mac string, ip string, ip6 string) (string, error) {
+	hostname := in.GetHostname()
+	if hostname == "" {
+		hostname = in.GetName()
+	}
+
+	seed := cloudInitSeed{
+		InstanceID:        fmt.Sprintf("%s-%d", in.GetName(), time.Now().Unix()),
+		Hostname:          hostname,
+		SSHAuthorizedKeys: in.GetSshAuthorizedKeys(),
+		UserData:          in.GetUserData(),
+
+		MAC:      mac,
+		IP:       ip,
+		Gateway:  ipString(network.Gateway),
+		IPv6:     ip6,
+		Gateway6: ipString(network.Gateway6),
+	}
+	seed.Prefix, _ = network.Subnet.Mask.Size()
+	if network.Subnet6 != nil {
+		seed.Prefix6, _ = network.Subnet6.Mask.Size()
+	}
+
+	files, err := seed.files()
+	if err != nil {
+		return "", err
+	}
+	return s.seeds.CreateSeed(ctx, in.GetName(), files)
+}
@@ -0,0 +1,417 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"regexp"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/glog"
+	libvirt "github.com/libvirt/libvirt-go"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+var (
+	diskDetachTimeout = flag.Duration("disk-detach-timeout", 30*time.Second, "time to wait for a running guest to release a detached disk")
+)
+
+const maxDataDisks = 16
+
+const detachPollInterval = 500 * time.Millisecond
+
+var diskNameRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,30}[a-z0-9])?$`)
+
+// diskStorageName is the volume name of a data disk. VM names that pass
+// validateVMName have no underscores, so it can't clash with the boot volume
+// of another VM.
+func diskStorageName(vmName string, disk string) string {
+	return vmName + "_" + disk
+}
+
+// diskDevice is the libvirt device definition of a data disk.
+type diskDevice struct {
+	XMLName xml.Name `xml:"disk"`
+	Type    string   `xml:"type,attr"`
+	Device  string   `xml:"device,attr"`
+	Driver  struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"driver"`
+	Source struct {
+		Dev  string `xml:"dev,attr,omitempty"`
+		File string `xml:"file,attr,omitempty"`
+	} `xml:"source"`
+	Target libvirtDiskTarget `xml:"target"`
+	Serial string            `xml:"serial"`
+}
+
+// diskDeviceXML renders the device of a data disk, a block device for raw
+// volumes and a file otherwise.
+func (s Server) diskDeviceXML(vmName string, disk vmDisk) (string, error) {
+	dev := diskDevice{Device: "disk", Serial: disk.Name}
+	dev.Driver.Name = "qemu"
+	dev.Driver.Type = s.storage.StorageFormat()
+
+	path := s.storage.StorageBlockDevice(diskStorageName(vmName, disk.Name))
+	if dev.Driver.Type == "raw" {
+		dev.Type = "block"
+		dev.Source.Dev = path
+	} else {
+		dev.Type = "file"
+		dev.Source.File = path
+	}
+	dev.Target = libvirtDiskTarget{Dev: disk.Target, Bus: "virtio"}
+
+	b, err := xml.Marshal(dev)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// freeDiskTarget returns the first virtio device name not used by any of the
+// domain definitions.
+func freeDiskTarget(doms ...libvirtDomain) (string, error) {
+	used := map[string]bool{}
+	for _, dom := range doms {
+		for _, disk := range dom.Devices.Disk {
+			used[disk.Target.Dev] = true
+		}
+		for _, disk := range dom.Metadata.VMRegistry.Disks {
+			used[disk.Target] = true
+		}
+	}
+
+	for c := 'b'; c <= 'z'; c++ {
+		target := fmt.Sprintf("vd%c", c)
+		if !used[target] {
+			return target, nil
+		}
+	}
+	return "", fmt.Errorf("no free disk target")
+}
+
+// deviceModifyFlags applies device changes to the persistent definition, and
+// to the running domain if there is one.
+func deviceModifyFlags(d libvirt.Domain) (libvirt.DomainDeviceModifyFlags, bool, error) {
+	active, err := d.IsActive()
+	if err != nil {
+		return 0, false, err
+	}
+	if active {
+		return libvirt.DOMAIN_DEVICE_MODIFY_CONFIG | libvirt.DOMAIN_DEVICE_MODIFY_LIVE, true, nil
+	}
+	return libvirt.DOMAIN_DEVICE_MODIFY_CONFIG, false, nil
+}
+
+func (s Server) describeDisk(vmName string, disk vmDisk) *pb.Disk {
+	return &pb.Disk{
+		Name:      disk.Name,
+		Size:      disk.Size,
+		Target:    disk.Target,
+		Retain:    disk.Retain,
+		Detaching: disk.Detaching,
+		Path:      s.storage.StorageBlockDevice(diskStorageName(vmName, disk.Name)),
+	}
+}
+
+// AttachDisk is GRPC handler for AttachDisk API. It creates a blank volume and
+// attaches it to the VM, live if it's running.
+func (s Server) AttachDisk(ctx context.Context, in *pb.AttachDiskRequest) (*pb.Disk, error) {
+	name := in.GetName()
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+	defer s.locks.lock(name)()
+
+	if err := validateVMName(name); err != nil {
+		return nil, grpc.Errorf(codes.FailedPrecondition, "vm can't have data disks: %v", err)
+	}
+	if !diskNameRe.MatchString(in.GetDisk()) {
+		return nil, grpc.Errorf(codes.InvalidArgument, "invalid disk name %q", in.GetDisk())
+	}
+	if in.GetSize() == 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "size not specified")
+	}
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	md := config.Metadata.VMRegistry
+
+	for _, disk := range md.Disks {
+		if disk.Name == in.GetDisk() {
+			return nil, grpc.Errorf(codes.AlreadyExists, "disk %s of %s already exists", disk.Name, name)
+		}
+	}
+	if len(md.Disks) >= maxDataDisks {
+		return nil, grpc.Errorf(codes.ResourceExhausted, "%s already has %d disks", name, len(md.Disks))
+	}
+
+	flags, active, err := deviceModifyFlags(*d)
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+
+	doms := []libvirtDomain{config}
+	if active {
+		live, err := parseLiveDomain(ctx, *d)
+		if err != nil {
+			return nil, err
+		}
+		doms = append(doms, live)
+	}
+	target, err := freeDiskTarget(doms...)
+	if err != nil {
+		return nil, grpc.Errorf(codes.ResourceExhausted, "failed to attach disk to %s: %v", name, err)
+	}
+
+	disk := vmDisk{
+		Name:   in.GetDisk(),
+		Size:   in.GetSize(),
+		Target: target,
+		Retain: in.GetRetain(),
+	}
+	storageName := diskStorageName(name, disk.Name)
+
+	sg := newSaga("attach disk " + storageName)
+	defer sg.rollbackUnlessCommitted(ctx)
+
+	err = s.storage.CreateStorage(ctx, storageName, disk.Size, "")
+	if grpc.Code(err) == codes.AlreadyExists {
+		return nil, err
+	}
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to create storage: %v", err)
+	}
+	sg.onRollback("storage", func(ctx context.Context) error {
+		return s.storage.RemoveStorage(ctx, storageName)
+	})
+
+	devXML, err := s.diskDeviceXML(name, disk)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to render disk device: %v", err)
+	}
+
+	err = traceDomainAction(ctx, "AttachDeviceFlags", func() error {
+		return d.AttachDeviceFlags(devXML, flags)
+	})
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to attach disk: %v", err)
+	}
+	sg.onRollback("disk attachment", func(ctx context.Context) error {
+		return traceDomainAction(ctx, "DetachDeviceFlags", func() error {
+			return d.DetachDeviceFlags(devXML, flags)
+		})
+	})
+
+	md.Disks = append(md.Disks, disk)
+	err = traceDomainSetMetadata(ctx, *d, md)
+	if err != nil {
+		return nil, err
+	}
+
+	sg.commit()
+	return s.describeDisk(name, disk), nil
+}
+
+// waitForDiskRelease polls the live domain until the guest releases the
+// disk target, or timeout expires.
+func waitForDiskRelease(ctx context.Context, d *libvirt.Domain, target string, timeout time.Duration) (bool, error) {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(detachPollInterval)
+	defer ticker.Stop()
+
+	for {
+		live, err := parseLiveDomain(ctx, *d)
+		if err != nil {
+			return false, err
+		}
+		attached := false
+		for _, disk := range live.Devices.Disk {
+			attached = attached || disk.Target.Dev == target
+		}
+		if !attached {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-deadline:
+			return false, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// DetachDisk is GRPC handler for DetachDisk API. The volume is removed unless
+// the disk is retained.
+//
+// A running guest releases the disk asynchronously, so the disk is recorded
+// as detaching until it's gone from the live domain and its volume removed.
+// If that doesn't happen in time the call fails, and retrying it finishes the
+// detach.
+func (s Server) DetachDisk(ctx context.Context, in *pb.DetachDiskRequest) (*pb.Disk, error) {
+	name := in.GetName()
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+	defer s.locks.lock(name)()
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	md := config.Metadata.VMRegistry
+
+	var disk *vmDisk
+	disks := []vmDisk{}
+	for i := range md.Disks {
+		if md.Disks[i].Name == in.GetDisk() {
+			disk = &md.Disks[i]
+		} else {
+			disks = append(disks, md.Disks[i])
+		}
+	}
+	if disk == nil {
+		return nil, grpc.Errorf(codes.NotFound, "disk %s of %s not found", in.GetDisk(), name)
+	}
+
+	flags, active, err := deviceModifyFlags(*d)
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+
+	devXML, err := s.diskDeviceXML(name, *disk)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to render disk device: %v", err)
+	}
+
+	if disk.Detaching {
+		// the device is already gone from the definition, ask the guest to
+		// release it again in case it missed the first request.
+		if active {
+			err = traceDomainAction(ctx, "DetachDeviceFlags", func() error {
+				return d.DetachDeviceFlags(devXML, libvirt.DOMAIN_DEVICE_MODIFY_LIVE)
+			})
+			if err != nil {
+				glog.Infof("repeated detach of disk %s of %s failed: %v", disk.Name, name, err)
+			}
+		}
+	} else {
+		err = traceDomainAction(ctx, "DetachDeviceFlags", func() error {
+			return d.DetachDeviceFlags(devXML, flags)
+		})
+		if err != nil {
+			return nil, grpc.Errorf(libvirtErrorCode(err), "failed to detach disk: %v", err)
+		}
+
+		disk.Detaching = true
+		err = traceDomainSetMetadata(ctx, *d, md)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if active {
+		released, err := waitForDiskRelease(ctx, d, disk.Target, *diskDetachTimeout)
+		if err != nil {
+			return nil, grpc.Errorf(codes.Unavailable, "failed to wait for disk %s of %s to be released, retry to finish the detach: %v", disk.Name, name, err)
+		}
+		if !released {
+			return nil, grpc.Errorf(codes.Unavailable, "guest hasn't released disk %s of %s yet, retry to finish the detach", disk.Name, name)
+		}
+	}
+
+	if !disk.Retain {
+		err = s.removeDiskSnapshots(ctx, name, disk.Name, md.Snapshots)
+		if err != nil {
+			glog.Warningf("failed to remove snapshots of detached disk %s of %s: %v", disk.Name, name, err)
+		}
+
+		err = s.storage.RemoveStorage(ctx, diskStorageName(name, disk.Name))
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "disk detached, but failed to remove its storage, retry to finish the detach: %v", err)
+		}
+	}
+
+	detached := *disk
+	md.Disks = disks
+	err = traceDomainSetMetadata(ctx, *d, md)
+	if err != nil {
+		return nil, err
+	}
+	detached.Detaching = false
+	return s.describeDisk(name, detached), nil
+}
+
+// ListDisks is GRPC handler for ListDisks API.
+func (s Server) ListDisks(ctx context.Context, in *pb.ListDisksRequest) (*pb.ListDisksReply, error) {
+	d, err := traceGetDomainByName(ctx, s.conn, in.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := []*pb.Disk{}
+	for _, disk := range config.Metadata.VMRegistry.Disks {
+		disks = append(disks, s.describeDisk(in.GetName(), disk))
+	}
+	return &pb.ListDisksReply{Disks: disks}, nil
+}
+
+// removeDataDisks removes the volumes of the data disks of a destroyed VM,
+// except for the retained ones.
+func (s Server) removeDataDisks(ctx context.Context, vmName string, disks []vmDisk) error {
+	for _, disk := range disks {
+		storageName := diskStorageName(vmName, disk.Name)
+		if disk.Retain {
+			glog.Infof("keeping retained disk %s of %s", storageName, vmName)
+			continue
+		}
+		if err := s.storage.RemoveStorage(ctx, storageName); err != nil {
+			return fmt.Errorf("failed to remove disk %s: %v", disk.Name, err)
+		}
+	}
+	return nil
+}
@@ -19,29 +19,209 @@ limitations under the License.
 package server
 
 import (
+	"fmt"
+	"net"
 	"strings"
 
-	"github.com/google/vmregistry/powerdns"
+	"golang.org/x/net/context"
 )
 
-type DnsClient struct {
-	cli    *powerdns.PowerDNS
-	domain string
+// dnsTTL is the ttl of the records published for VMs.
+const dnsTTL = 300
+
+// DNSRecord is a single resource record. Name is fully qualified.
+type DNSRecord struct {
+	Name    string
+	Type    string
+	TTL     int
+	Content string
+}
+
+// DNSProvider manages records in the dns zones hosting VMs. Add and Remove
+// work on the whole record set of the record's name and type.
+type DNSProvider interface {
+	// AddRecord creates or replaces the record set with the single record.
+	AddRecord(zone string, rec DNSRecord) error
+	// RemoveRecord deletes the record set.
+	RemoveRecord(zone string, rec DNSRecord) error
+	// ListRecords returns all the records of the zone.
+	ListRecords(zone string) ([]DNSRecord, error)
+}
+
+func canonicalZone(zone string) string {
+	if !strings.HasSuffix(zone, ".") {
+		zone = zone + "."
+	}
+	return zone
+}
+
+// addressRecordType returns AAAA for ipv6 addresses and A for everything else.
+func addressRecordType(address string) string {
+	ip := net.ParseIP(address)
+	if ip != nil && ip.To4() == nil {
+		return "AAAA"
+	}
+	return "A"
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name of the address.
+func reverseName(address string) (string, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("invalid ip address %q", address)
+	}
+
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+
+	const hexDigits = "0123456789abcdef"
+	ip = ip.To16()
+	name := make([]byte, 0, 4*len(ip)+len("ip6.arpa."))
+	for i := len(ip) - 1; i >= 0; i-- {
+		name = append(name, hexDigits[ip[i]&0xf], '.', hexDigits[ip[i]>>4], '.')
+	}
+	return string(append(name, "ip6.arpa."...)), nil
 }
 
-func NewDNSClient(baseURL string, domain string, apikey string) *DnsClient {
-	if !strings.HasSuffix(domain, ".") {
-		domain = domain + "."
+// reverseAddress parses an in-addr.arpa or ip6.arpa name back to the address.
+// Returns nil for any other name.
+func reverseAddress(name string) net.IP {
+	name = strings.ToLower(canonicalZone(name))
+
+	var labels []string
+	var address string
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa."):
+		labels = strings.Split(strings.TrimSuffix(name, ".in-addr.arpa."), ".")
+		if len(labels) != net.IPv4len {
+			return nil
+		}
+		for i := len(labels) - 1; i >= 0; i-- {
+			address += labels[i]
+			if i > 0 {
+				address += "."
+			}
+		}
+	case strings.HasSuffix(name, ".ip6.arpa."):
+		labels = strings.Split(strings.TrimSuffix(name, ".ip6.arpa."), ".")
+		if len(labels) != 2*net.IPv6len {
+			return nil
+		}
+		for i := len(labels) - 1; i >= 0; i-- {
+			if len(labels[i]) != 1 {
+				return nil
+			}
+			address += labels[i]
+			if i > 0 && i%4 == 0 {
+				address += ":"
+			}
+		}
+	default:
+		return nil
 	}
-	return &DnsClient{cli: powerdns.New(baseURL, "localhost", domain, apikey), domain: domain}
+	return net.ParseIP(address)
 }
 
-func (c DnsClient) Add(name string, address string) error {
-	_, err := c.cli.AddRecord(name+"."+c.domain, "A", 300, []string{address})
-	return err
+// ptrName returns the reverse name of the address, making sure it belongs to
+// the reverse zone.
+func ptrName(zone string, address string) (string, error) {
+	name, err := reverseName(address)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(name, "."+canonicalZone(zone)) {
+		return "", fmt.Errorf("%s is not in reverse zone %s", name, zone)
+	}
+	return name, nil
 }
 
-func (c DnsClient) Remove(name string, address string) error {
-	_, err := c.cli.DeleteRecord(name+"."+c.domain, "A", 300, []string{address})
-	return err
+// zoneRecord is a record along with the zone it belongs to.
+type zoneRecord struct {
+	Zone string
+	DNSRecord
+}
+
+// vmRecords returns the address records of the VM, and the pointer records if
+// the network has reverse zones.
+func vmRecords(network *Network, name string, addresses ...string) ([]zoneRecord, error) {
+	fqdn := name + "." + canonicalZone(network.DNSZone)
+
+	records := []zoneRecord{}
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+
+		records = append(records, zoneRecord{
+			Zone: network.DNSZone,
+			DNSRecord: DNSRecord{
+				Name:    fqdn,
+				Type:    addressRecordType(address),
+				TTL:     dnsTTL,
+				Content: address,
+			},
+		})
+
+		zone := network.reverseZoneOf(address)
+		if zone == "" {
+			continue
+		}
+		ptr, err := ptrName(zone, address)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, zoneRecord{
+			Zone: zone,
+			DNSRecord: DNSRecord{
+				Name:    ptr,
+				Type:    "PTR",
+				TTL:     dnsTTL,
+				Content: fqdn,
+			},
+		})
+	}
+
+	return records, nil
+}
+
+// publishDNS adds the address records of the VM, and the pointer records if
+// the network has reverse zones. Removal of each added record is registered
+// with the saga.
+func (s Server) publishDNS(sg *saga, network *Network, name string, addresses ...string) error {
+	records, err := vmRecords(network, name, addresses...)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		rec := rec
+
+		err := s.dns.AddRecord(rec.Zone, rec.DNSRecord)
+		if err != nil {
+			return err
+		}
+		sg.onRollback("dns record "+rec.Name+" "+rec.Type, func(ctx context.Context) error {
+			return s.dns.RemoveRecord(rec.Zone, rec.DNSRecord)
+		})
+	}
+
+	return nil
+}
+
+// unpublishDNS removes the address and pointer records of the VM.
+func (s Server) unpublishDNS(network *Network, name string, addresses ...string) error {
+	records, err := vmRecords(network, name, addresses...)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		err := s.dns.RemoveRecord(rec.Zone, rec.DNSRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
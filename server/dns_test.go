@@ -0,0 +1,133 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"testing"
+)
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		wantErr bool
+	}{
+		{address: "10.0.1.2", want: "2.1.0.10.in-addr.arpa."},
+		{address: "192.168.100.254", want: "254.100.168.192.in-addr.arpa."},
+		{address: "::ffff:10.0.1.2", want: "2.1.0.10.in-addr.arpa."},
+		{address: "2001:db8::567:89ab", want: "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{address: "fd00::1", want: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa."},
+		{address: "", wantErr: true},
+		{address: "10.0.1", wantErr: true},
+		{address: "vm1.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := reverseName(tt.address)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("reverseName(%q) = %q, want an error", tt.address, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("reverseName(%q) failed: %v", tt.address, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("reverseName(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestReverseAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "2.1.0.10.in-addr.arpa.", want: "10.0.1.2"},
+		{name: "2.1.0.10.IN-ADDR.ARPA", want: "10.0.1.2"},
+		{name: "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", want: "2001:db8::567:89ab"},
+		{name: "1.0.10.in-addr.arpa.", want: ""},
+		{name: "300.1.0.10.in-addr.arpa.", want: ""},
+		{name: "1.0.0.0.ip6.arpa.", want: ""},
+		{name: "10.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.", want: ""},
+		{name: "g.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.", want: ""},
+		{name: "vm1.example.com.", want: ""},
+	}
+
+	for _, tt := range tests {
+		got := reverseAddress(tt.name)
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("reverseAddress(%q) = %v, want nil", tt.name, got)
+			}
+			continue
+		}
+		if got == nil || got.String() != tt.want {
+			t.Errorf("reverseAddress(%q) = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReverseNameRoundTrip(t *testing.T) {
+	for _, address := range []string{"10.0.1.2", "0.0.0.0", "255.255.255.255", "2001:db8::1", "fd00:1:2:3:4:5:6:7"} {
+		name, err := reverseName(address)
+		if err != nil {
+			t.Errorf("reverseName(%q) failed: %v", address, err)
+			continue
+		}
+		if got := reverseAddress(name); got == nil || got.String() != address {
+			t.Errorf("reverseAddress(reverseName(%q)) = %v", address, got)
+		}
+	}
+}
+
+func TestPTRName(t *testing.T) {
+	tests := []struct {
+		zone    string
+		address string
+		want    string
+		wantErr bool
+	}{
+		{zone: "0.10.in-addr.arpa", address: "10.0.1.2", want: "2.1.0.10.in-addr.arpa."},
+		{zone: "0.10.in-addr.arpa.", address: "10.0.1.2", want: "2.1.0.10.in-addr.arpa."},
+		{zone: "8.b.d.0.1.0.0.2.ip6.arpa.", address: "2001:db8::1", want: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+		{zone: "1.10.in-addr.arpa", address: "10.0.1.2", wantErr: true},
+		{zone: "0.10.in-addr.arpa", address: "2001:db8::1", wantErr: true},
+		{zone: "0.10.in-addr.arpa", address: "not-an-ip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ptrName(tt.zone, tt.address)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ptrName(%q, %q) = %q, want an error", tt.zone, tt.address, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ptrName(%q, %q) failed: %v", tt.zone, tt.address, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ptrName(%q, %q) = %q, want %q", tt.zone, tt.address, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// dnsRecordOwner is implemented by providers that know which record sets
+// vmregistry published. The dns reconciler only removes those.
+type dnsRecordOwner interface {
+	owns(zone string, rec DNSRecord) bool
+}
+
+type dnsLedgerState struct {
+	// RRSets are "name type" keys by zone.
+	RRSets map[string][]string `json:"rrsets"`
+}
+
+// dnsLedger is a DNSProvider recording the record sets added through it in a
+// state file, and forgetting them once removed.
+type dnsLedger struct {
+	DNSProvider
+
+	mu        sync.Mutex
+	statePath string
+	rrsets    map[string]map[string]bool
+}
+
+// NewDNSLedger wraps the provider to keep track of the record sets it
+// publishes in the state file at statePath.
+func NewDNSLedger(provider DNSProvider, statePath string) (DNSProvider, error) {
+	l := &dnsLedger{
+		DNSProvider: provider,
+		statePath:   statePath,
+		rrsets:      map[string]map[string]bool{},
+	}
+
+	data, err := ioutil.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dns state: %v", err)
+	}
+
+	state := dnsLedgerState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse dns state %s: %v", statePath, err)
+	}
+	for zone, keys := range state.RRSets {
+		l.rrsets[zone] = map[string]bool{}
+		for _, key := range keys {
+			l.rrsets[zone][key] = true
+		}
+	}
+	return l, nil
+}
+
+func ledgerKeys(zone string, rec DNSRecord) (string, string) {
+	return strings.ToLower(canonicalZone(zone)), strings.ToLower(canonicalZone(rec.Name)) + " " + rec.Type
+}
+
+// save writes the state file atomically. Must be called with mu held.
+func (l *dnsLedger) save() error {
+	state := dnsLedgerState{RRSets: map[string][]string{}}
+	for zone, keys := range l.rrsets {
+		for key := range keys {
+			state.RRSets[zone] = append(state.RRSets[zone], key)
+		}
+		sort.Strings(state.RRSets[zone])
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(l.statePath, data); err != nil {
+		return fmt.Errorf("failed to save dns state: %v", err)
+	}
+	return nil
+}
+
+// track adds the set to the state. Returns true if it wasn't tracked yet.
+func (l *dnsLedger) track(zone string, rec DNSRecord) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	z, key := ledgerKeys(zone, rec)
+	if l.rrsets[z][key] {
+		return false, nil
+	}
+	if l.rrsets[z] == nil {
+		l.rrsets[z] = map[string]bool{}
+	}
+	l.rrsets[z][key] = true
+	if err := l.save(); err != nil {
+		delete(l.rrsets[z], key)
+		return false, err
+	}
+	return true, nil
+}
+
+// untrack drops the set from the state.
+func (l *dnsLedger) untrack(zone string, rec DNSRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	z, key := ledgerKeys(zone, rec)
+	if !l.rrsets[z][key] {
+		return nil
+	}
+	delete(l.rrsets[z], key)
+	return l.save()
+}
+
+// AddRecord records the set before publishing it, so that a failed save
+// never leaves a set behind that isn't tracked. The provider is called
+// without holding the lock, a slow dns server only holds up its own calls.
+func (l *dnsLedger) AddRecord(zone string, rec DNSRecord) error {
+	added, err := l.track(zone, rec)
+	if err != nil {
+		return err
+	}
+
+	err = l.DNSProvider.AddRecord(zone, rec)
+	if err != nil && added {
+		// the set may be someone else's, don't claim it.
+		if uerr := l.untrack(zone, rec); uerr != nil {
+			glog.Warningf("failed to untrack %s %s in %s: %v", rec.Name, rec.Type, zone, uerr)
+		}
+	}
+	return err
+}
+
+func (l *dnsLedger) RemoveRecord(zone string, rec DNSRecord) error {
+	if err := l.DNSProvider.RemoveRecord(zone, rec); err != nil {
+		return err
+	}
+	return l.untrack(zone, rec)
+}
+
+func (l *dnsLedger) owns(zone string, rec DNSRecord) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	z, key := ledgerKeys(zone, rec)
+	return l.rrsets[z][key]
+}
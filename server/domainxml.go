@@ -0,0 +1,216 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// domainSpec is everything a new domain is built from.
+type domainSpec struct {
+	Name     string
+	Memory   uint64
+	Cores    uint32
+	DiskPath string
+	IP       string
+	IPv6     string
+	MAC      string
+	// SeedPath is the cloud-init seed image, if any.
+	SeedPath string
+	Metadata vmMetadata
+	Network  *Network
+}
+
+// domainTemplateData is passed to the domain xml template. All the strings
+// are xml-escaped, so templates can use them both in text and attributes.
+type domainTemplateData struct {
+	Name     string
+	Memory   uint64
+	Cores    uint32
+	DiskPath string
+	IP       string
+	MAC      string
+	SeedPath string
+	// Metadata is the vmregistry metadata element.
+	Metadata string
+
+	Network        string
+	Bridge         string
+	LibvirtNetwork string
+	Gateway        string
+	Netmask        string
+	Prefix         int
+
+	IPv6       string
+	Gateway6   string
+	IPv6Prefix int
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// newDomainTemplateData fills the template values in from the spec.
+func newDomainTemplateData(spec domainSpec) (domainTemplateData, error) {
+	metadata, err := spec.Metadata.marshal()
+	if err != nil {
+		return domainTemplateData{}, err
+	}
+
+	n := spec.Network
+	prefix, _ := n.Subnet.Mask.Size()
+	prefix6 := 0
+	if n.Subnet6 != nil {
+		prefix6, _ = n.Subnet6.Mask.Size()
+	}
+
+	return domainTemplateData{
+		Name:     escapeXML(spec.Name),
+		Memory:   spec.Memory,
+		Cores:    spec.Cores,
+		DiskPath: escapeXML(spec.DiskPath),
+		IP:       escapeXML(spec.IP),
+		MAC:      escapeXML(spec.MAC),
+		SeedPath: escapeXML(spec.SeedPath),
+		Metadata: metadata,
+
+		Network:        escapeXML(n.Name),
+		Bridge:         escapeXML(n.Bridge),
+		LibvirtNetwork: escapeXML(n.LibvirtNetwork),
+		Gateway:        escapeXML(ipString(n.Gateway)),
+		Netmask:        escapeXML(net.IP(n.Subnet.Mask).String()),
+		Prefix:         prefix,
+
+		IPv6:       escapeXML(spec.IPv6),
+		Gateway6:   escapeXML(ipString(n.Gateway6)),
+		IPv6Prefix: prefix6,
+	}, nil
+}
+
+// checkDomain verifies that the domain parsed back from the rendered xml is
+// the one asked for, and that vmregistry can manage it.
+func checkDomain(dom libvirtDomain, spec domainSpec) error {
+	if dom.Name != spec.Name {
+		return fmt.Errorf("domain name is %q instead of .Name", dom.Name)
+	}
+	if err := checkMetadata(dom.Metadata.VMRegistry, spec.Metadata); err != nil {
+		return err
+	}
+	if len(dom.Devices.Interface) == 0 {
+		return fmt.Errorf("domain has no network interface")
+	}
+
+	if !hasDisk(dom, spec.DiskPath) {
+		return fmt.Errorf("domain has no disk with .DiskPath as source")
+	}
+	return nil
+}
+
+// checkMetadata verifies that the metadata parsed back is the one rendered
+// into .Metadata. Comparing the marshaled forms checks every field, so a
+// template can't keep only some of them.
+func checkMetadata(got vmMetadata, want vmMetadata) error {
+	gotXML, err := got.marshal()
+	if err != nil {
+		return err
+	}
+	wantXML, err := want.marshal()
+	if err != nil {
+		return err
+	}
+	if gotXML != wantXML {
+		return fmt.Errorf("domain metadata must be .Metadata, unchanged")
+	}
+	return nil
+}
+
+// hasDisk tells if the domain has a disk backed by the path.
+func hasDisk(dom libvirtDomain, path string) bool {
+	for _, disk := range dom.Devices.Disk {
+		if disk.Source.Dev == path || disk.Source.File == path {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMAC tells if the domain has an interface with the mac.
+func hasMAC(dom libvirtDomain, mac string) bool {
+	for _, iface := range dom.Devices.Interface {
+		if strings.EqualFold(iface.Mac.Address, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildDomainXML renders the domain xml of a new VM and validates it by
+// parsing it back, so that a broken template fails before the domain is
+// defined.
+func buildDomainXML(tpl *DomainTemplate, spec domainSpec) (string, error) {
+	data, err := newDomainTemplateData(spec)
+	if err != nil {
+		return "", grpc.Errorf(codes.Internal, "failed to render vm metadata: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.tpl.Execute(&buf, data); err != nil {
+		return "", grpc.Errorf(codes.Internal, "failed to render domain template %s: %v", tpl.Name, err)
+	}
+	domXML := buf.String()
+
+	dom := libvirtDomain{}
+	if err := xml.Unmarshal([]byte(domXML), &dom); err != nil {
+		return "", grpc.Errorf(codes.Internal, "domain template %s rendered malformed xml: %v", tpl.Name, err)
+	}
+	if err := checkDomain(dom, spec); err != nil {
+		return "", grpc.Errorf(codes.Internal, "domain template %s rendered an invalid domain: %v", tpl.Name, err)
+	}
+
+	if spec.SeedPath != "" && !hasDisk(dom, spec.SeedPath) {
+		return "", grpc.Errorf(codes.FailedPrecondition, "domain template %s doesn't attach .SeedPath, can't use cloud-init", tpl.Name)
+	}
+
+	// eui-64 addresses are derived from the mac, and the cloud-init network
+	// config matches the interface by it.
+	if spec.Network.EUI64 && !hasMAC(dom, spec.MAC) {
+		return "", grpc.Errorf(codes.FailedPrecondition, "domain template %s must set the interface mac to .MAC for eui-64 addressing", tpl.Name)
+	}
+	if spec.SeedPath != "" && !hasMAC(dom, spec.MAC) {
+		return "", grpc.Errorf(codes.FailedPrecondition, "domain template %s must set the interface mac to .MAC to use cloud-init", tpl.Name)
+	}
+
+	return domXML, nil
+}
@@ -0,0 +1,210 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/glog"
+	"github.com/libvirt/libvirt-go"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+const (
+	// domainEventBacklog is the number of libvirt events waiting to be
+	// dispatched before the callback starts dropping them.
+	domainEventBacklog = 1024
+	// subscriberBacklog is the number of events a subscriber can lag behind
+	// before it is disconnected.
+	subscriberBacklog = 256
+)
+
+// domainEvent is a lifecycle change reported by libvirt.
+type domainEvent struct {
+	name  string
+	event pb.VMEvent_Type
+	time  time.Time
+}
+
+// eventHub fans VM events out to the subscribers.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan *pb.VMEvent]bool
+
+	domainEvents chan domainEvent
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers:  map[chan *pb.VMEvent]bool{},
+		domainEvents: make(chan domainEvent, domainEventBacklog),
+	}
+}
+
+// subscribe returns a channel receiving all the events published from now on,
+// and a function to unsubscribe. The channel is closed if the subscriber falls
+// too far behind.
+func (h *eventHub) subscribe() (<-chan *pb.VMEvent, func()) {
+	ch := make(chan *pb.VMEvent, subscriberBacklog)
+
+	h.mu.Lock()
+	h.subscribers[ch] = true
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.subscribers[ch] {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *eventHub) publish(ev *pb.VMEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			glog.Warningf("event subscriber fell behind, disconnecting it")
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// vmEventType maps libvirt lifecycle events to VM events. Returns UNKNOWN for
+// the events that aren't reported.
+func vmEventType(event *libvirt.DomainEventLifecycle) pb.VMEvent_Type {
+	switch event.Event {
+	case libvirt.DOMAIN_EVENT_DEFINED:
+		if event.Detail == int(libvirt.DOMAIN_EVENT_DEFINED_ADDED) {
+			return pb.VMEvent_CREATED
+		}
+	case libvirt.DOMAIN_EVENT_UNDEFINED:
+		return pb.VMEvent_DESTROYED
+	case libvirt.DOMAIN_EVENT_STARTED:
+		return pb.VMEvent_STARTED
+	case libvirt.DOMAIN_EVENT_STOPPED:
+		if event.Detail == int(libvirt.DOMAIN_EVENT_STOPPED_CRASHED) {
+			return pb.VMEvent_CRASHED
+		}
+		return pb.VMEvent_STOPPED
+	case libvirt.DOMAIN_EVENT_CRASHED:
+		return pb.VMEvent_CRASHED
+	case libvirt.DOMAIN_EVENT_SUSPENDED, libvirt.DOMAIN_EVENT_PMSUSPENDED:
+		return pb.VMEvent_SUSPENDED
+	case libvirt.DOMAIN_EVENT_RESUMED:
+		return pb.VMEvent_RESUMED
+	}
+	return pb.VMEvent_UNKNOWN
+}
+
+// onLifecycleEvent runs in the libvirt event loop, so it only queues the event.
+// Events that aren't reported to watchers are queued as well, to keep the
+// inventory fresh.
+func (s Server) onLifecycleEvent(c *libvirt.Connect, d *libvirt.Domain, event *libvirt.DomainEventLifecycle) {
+	eventType := vmEventType(event)
+
+	name, err := d.GetName()
+	if err != nil {
+		glog.Errorf("failed to get name of domain with event %v: %v", event.Event, err)
+		return
+	}
+
+	select {
+	case s.events.domainEvents <- domainEvent{name: name, event: eventType, time: time.Now()}:
+	default:
+		glog.Errorf("dropping %v event of %s, event queue is full", eventType, name)
+	}
+}
+
+// dispatchEvents updates the inventory with the queued events and publishes
+// them along with the VM details.
+func (s Server) dispatchEvents() {
+	ctx := context.Background()
+
+	for de := range s.events.domainEvents {
+		if de.event == pb.VMEvent_DESTROYED {
+			s.inventory.remove(de.name)
+		} else {
+			s.refreshInventory(ctx, de.name)
+		}
+
+		if de.event == pb.VMEvent_UNKNOWN {
+			continue
+		}
+
+		ev := &pb.VMEvent{
+			Type: de.event,
+			Name: de.name,
+			Time: de.time.Unix(),
+		}
+		if de.event != pb.VMEvent_DESTROYED {
+			ev.Vm, _ = s.inventory.get(de.name)
+		}
+
+		s.events.publish(ev)
+	}
+}
+
+// WatchDomainEvents subscribes to libvirt lifecycle events of all domains. The
+// libvirt event loop must be registered before the connection is opened, and
+// run by the caller.
+func (s Server) WatchDomainEvents() error {
+	_, err := s.conn.DomainEventLifecycleRegister(nil, s.onLifecycleEvent)
+	if err != nil {
+		return err
+	}
+
+	go s.dispatchEvents()
+	return nil
+}
+
+// Watch is GRPC handler for Watch API.
+func (s Server) Watch(req *pb.WatchRequest, stream pb.VMRegistry_WatchServer) error {
+	events, unsubscribe := s.events.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return grpc.Errorf(codes.ResourceExhausted, "watcher fell behind, events were dropped")
+			}
+			if req.GetName() != "" && ev.Name != req.GetName() {
+				continue
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
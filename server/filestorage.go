@@ -0,0 +1,197 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// FileStorage keeps VM disks as qcow2 files in a local directory, created
+// from the source images in another one. Disks are either thin overlays
+// backed by the source image, or full copies of it.
+type FileStorage struct {
+	dir       string
+	imagesDir string
+	overlay   bool
+	qemuImg   string
+	runner    CommandRunner
+}
+
+// NewFileStorage creates a storage writing disks to dir. mode is "overlay" or
+// "copy".
+func NewFileStorage(dir string, imagesDir string, mode string, qemuImg string, runner CommandRunner) (StorageManager, error) {
+	overlay := false
+	switch mode {
+	case "overlay":
+		overlay = true
+	case "copy":
+	default:
+		return nil, fmt.Errorf("unknown file storage mode %q", mode)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return FileStorage{dir: dir, imagesDir: imagesDir, overlay: overlay, qemuImg: qemuImg, runner: runner}, nil
+}
+
+// qemuImgInfo is the part of `qemu-img info` output we need.
+type qemuImgInfo struct {
+	Format      string `json:"format"`
+	VirtualSize uint64 `json:"virtual-size"`
+}
+
+func (s FileStorage) run(ctx context.Context, args ...string) ([]byte, error) {
+	return s.runner.Run(ctx, s.qemuImg, args...)
+}
+
+func (s FileStorage) info(ctx context.Context, path string) (qemuImgInfo, error) {
+	out, err := s.run(ctx, "info", "--output=json", path)
+	if err != nil {
+		return qemuImgInfo{}, err
+	}
+
+	info := qemuImgInfo{}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return qemuImgInfo{}, fmt.Errorf("failed to parse image info of %s: %v", path, err)
+	}
+	return info, nil
+}
+
+// sourcePath returns the path of a source image, which must be a file name
+// in the images directory.
+func (s FileStorage) sourcePath(sourceImage string) (string, error) {
+	if sourceImage != filepath.Base(sourceImage) || strings.HasPrefix(sourceImage, ".") {
+		return "", fmt.Errorf("invalid source image %q", sourceImage)
+	}
+	return filepath.Join(s.imagesDir, sourceImage), nil
+}
+
+// reserveFile creates the disk file empty, so that of two creates of the same
+// disk only one goes ahead. qemu-img then writes the image over it.
+func reserveFile(dest string) error {
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return grpc.Errorf(codes.AlreadyExists, "disk %s already exists", dest)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// CreateStorage fails with AlreadyExists if the disk file is there.
+func (s FileStorage) CreateStorage(ctx context.Context, name string, size uint64, sourceImage string) error {
+	dest := s.StorageBlockDevice(name)
+	if err := reserveFile(dest); err != nil {
+		return err
+	}
+
+	err := s.createImage(ctx, dest, size, sourceImage)
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
+
+// createImage writes the qcow2 image to the reserved path.
+func (s FileStorage) createImage(ctx context.Context, dest string, size uint64, sourceImage string) error {
+	sizeArg := strconv.FormatUint(size, 10)
+	if sourceImage == "" {
+		_, err := s.run(ctx, "create", "-f", "qcow2", dest, sizeArg)
+		return err
+	}
+
+	src, err := s.sourcePath(sourceImage)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.info(ctx, src)
+	if err != nil {
+		return err
+	}
+	if size < info.VirtualSize {
+		return fmt.Errorf("size %d is smaller than the %d bytes of source image %s", size, info.VirtualSize, sourceImage)
+	}
+
+	if s.overlay {
+		_, err = s.run(ctx, "create", "-f", "qcow2", "-o", "backing_file="+src+",backing_fmt="+info.Format, dest, sizeArg)
+		return err
+	}
+
+	_, err = s.run(ctx, "convert", "-f", info.Format, "-O", "qcow2", src, dest)
+	if err != nil {
+		return err
+	}
+	if size > info.VirtualSize {
+		_, err = s.run(ctx, "resize", "-f", "qcow2", dest, sizeArg)
+	}
+	return err
+}
+
+func (s FileStorage) RemoveStorage(ctx context.Context, name string) error {
+	err := os.Remove(s.StorageBlockDevice(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// ResizeStorage resizes the qcow2 image. It fails while the VM is running,
+// as qemu holds a lock on the image.
+func (s FileStorage) ResizeStorage(ctx context.Context, name string, size uint64) error {
+	_, err := s.run(ctx, "resize", "--shrink", "-f", "qcow2", s.StorageBlockDevice(name), strconv.FormatUint(size, 10))
+	return err
+}
+
+// CreateSnapshot adds an internal snapshot to the qcow2 image. Like the other
+// snapshot operations, it fails while the VM is running.
+func (s FileStorage) CreateSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "snapshot", "-c", snapshot, s.StorageBlockDevice(name))
+	return err
+}
+
+func (s FileStorage) RevertSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "snapshot", "-a", snapshot, s.StorageBlockDevice(name))
+	return err
+}
+
+func (s FileStorage) RemoveSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "snapshot", "-d", snapshot, s.StorageBlockDevice(name))
+	return err
+}
+
+func (s FileStorage) StorageBlockDevice(name string) string {
+	return filepath.Join(s.dir, name+".qcow2")
+}
+
+func (s FileStorage) StorageFormat() string {
+	return "qcow2"
+}
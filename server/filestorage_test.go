@@ -0,0 +1,83 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func newTestFileStorage(t *testing.T, r *fakeRunner) (StorageManager, string, func()) {
+	dir, err := ioutil.TempDir("", "filestorage")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	s, err := NewFileStorage(filepath.Join(dir, "vms"), filepath.Join(dir, "images"), "copy", "qemu-img", r)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	return s, filepath.Join(dir, "vms"), func() { os.RemoveAll(dir) }
+}
+
+func TestFileCreateStorageExisting(t *testing.T) {
+	r := newFakeRunner()
+	s, dir, cleanup := newTestFileStorage(t, r)
+	defer cleanup()
+
+	if err := s.CreateStorage(context.Background(), "vm1", 1024, ""); err != nil {
+		t.Fatalf("CreateStorage failed: %v", err)
+	}
+	checkCalls(t, r, "qemu-img create -f qcow2 "+filepath.Join(dir, "vm1.qcow2")+" 1024")
+
+	r.calls = nil
+	err := s.CreateStorage(context.Background(), "vm1", 1024, "")
+	if grpc.Code(err) != codes.AlreadyExists {
+		t.Errorf("CreateStorage of an existing disk returned %v, want AlreadyExists", err)
+	}
+	checkCalls(t, r)
+}
+
+func TestFileCreateStorageFailureRemovesDisk(t *testing.T) {
+	r := newFakeRunner()
+	s, dir, cleanup := newTestFileStorage(t, r)
+	defer cleanup()
+
+	dest := filepath.Join(dir, "vm1.qcow2")
+	r.errors["qemu-img create -f qcow2 "+dest+" 1024"] = errors.New("no space left on device")
+	if err := s.CreateStorage(context.Background(), "vm1", 1024, ""); err == nil {
+		t.Fatalf("CreateStorage succeeded despite qemu-img failing")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("failed create left %s behind: %v", dest, err)
+	}
+
+	delete(r.errors, "qemu-img create -f qcow2 "+dest+" 1024")
+	if err := s.CreateStorage(context.Background(), "vm1", 1024, ""); err != nil {
+		t.Errorf("CreateStorage after a failed create failed: %v", err)
+	}
+}
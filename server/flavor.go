@@ -0,0 +1,136 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// FlavorConfig is a named VM sizing, as loaded from the flavors file.
+type FlavorConfig struct {
+	Name   string `json:"name"`
+	Mem    uint64 `json:"mem"` // in GB
+	Cores  uint32 `json:"cores"`
+	DiskGB uint64 `json:"disk_gb"`
+	// Template is the name of the domain template used instead of the
+	// default one.
+	Template string `json:"template"`
+}
+
+// Flavor is a named VM sizing create requests can refer to.
+type Flavor struct {
+	Name     string
+	Mem      uint64
+	Cores    uint32
+	Size     uint64
+	Template string
+}
+
+// LoadFlavors reads a json list of flavors.
+func LoadFlavors(path string) ([]*Flavor, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := []FlavorConfig{}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	flavors := []*Flavor{}
+	seen := map[string]bool{}
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("flavor name not specified")
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("duplicate flavor %s", cfg.Name)
+		}
+		seen[cfg.Name] = true
+
+		if cfg.Mem == 0 || cfg.Cores == 0 || cfg.DiskGB == 0 {
+			return nil, fmt.Errorf("flavor %s must set mem, cores and disk_gb", cfg.Name)
+		}
+
+		flavors = append(flavors, &Flavor{
+			Name:     cfg.Name,
+			Mem:      cfg.Mem,
+			Cores:    cfg.Cores,
+			Size:     cfg.DiskGB << 30,
+			Template: cfg.Template,
+		})
+	}
+	return flavors, nil
+}
+
+// applyFlavor returns a copy of the request with the unset sizing and
+// template taken from its flavor.
+func (s Server) applyFlavor(in *pb.CreateRequest) (*pb.CreateRequest, error) {
+	if in.GetFlavor() == "" {
+		return in, nil
+	}
+
+	f, ok := s.flavors[in.GetFlavor()]
+	if !ok {
+		return nil, grpc.Errorf(codes.InvalidArgument, "unknown flavor %s", in.GetFlavor())
+	}
+
+	req := proto.Clone(in).(*pb.CreateRequest)
+	if req.Mem == 0 {
+		req.Mem = f.Mem
+	}
+	if req.Cores == 0 {
+		req.Cores = f.Cores
+	}
+	if req.Size == 0 {
+		req.Size = f.Size
+	}
+	if req.Template == "" {
+		req.Template = f.Template
+	}
+	return req, nil
+}
+
+// ListFlavors is GRPC handler for ListFlavors API.
+func (s Server) ListFlavors(ctx context.Context, in *pb.ListFlavorsRequest) (*pb.ListFlavorsReply, error) {
+	flavors := []*pb.Flavor{}
+	for _, f := range s.flavors {
+		flavors = append(flavors, &pb.Flavor{
+			Name:     f.Name,
+			Mem:      f.Mem,
+			Cores:    f.Cores,
+			Size:     f.Size,
+			Template: f.Template,
+		})
+	}
+	sort.Slice(flavors, func(i, j int) bool { return flavors[i].Name < flavors[j].Name })
+	return &pb.ListFlavorsReply{Flavors: flavors}, nil
+}
@@ -0,0 +1,261 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/glog"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// inventory caches the details of all VMs, indexed by name, ip and mac. VMs
+// stored in it are never modified in place, updates replace them.
+//
+// Every put and remove bumps a generation, and the generation of the last
+// update of each name is kept, removals included. A full resync reads the
+// generation before listing the domains, and leaves alone the names updated
+// since, as its view of them is older.
+type inventory struct {
+	mu      sync.RWMutex
+	byName  map[string]*pb.VM
+	byIP    map[string]*pb.VM
+	byMAC   map[string]*pb.VM
+	gen     uint64
+	updated map[string]uint64
+}
+
+func newInventory() *inventory {
+	return &inventory{
+		byName:  map[string]*pb.VM{},
+		byIP:    map[string]*pb.VM{},
+		byMAC:   map[string]*pb.VM{},
+		updated: map[string]uint64{},
+	}
+}
+
+func vmMACs(vm *pb.VM) []string {
+	macs := vm.Macs
+	if vm.Mac != "" {
+		macs = append([]string{vm.Mac}, macs...)
+	}
+	return macs
+}
+
+// unindex drops the VM from the ip and mac indexes. Must be called with mu
+// held.
+func (inv *inventory) unindex(vm *pb.VM) {
+	for _, ip := range []string{vm.Ip, vm.Ipv6} {
+		if inv.byIP[ip] == vm {
+			delete(inv.byIP, ip)
+		}
+	}
+	for _, mac := range vmMACs(vm) {
+		if inv.byMAC[strings.ToLower(mac)] == vm {
+			delete(inv.byMAC, strings.ToLower(mac))
+		}
+	}
+}
+
+// index adds the VM to all the indexes. Must be called with mu held.
+func (inv *inventory) index(vm *pb.VM) {
+	inv.byName[vm.Name] = vm
+	for _, ip := range []string{vm.Ip, vm.Ipv6} {
+		if ip != "" {
+			inv.byIP[ip] = vm
+		}
+	}
+	for _, mac := range vmMACs(vm) {
+		inv.byMAC[strings.ToLower(mac)] = vm
+	}
+}
+
+// store replaces the VM in the indexes. Must be called with mu held.
+func (inv *inventory) store(vm *pb.VM) {
+	if old, ok := inv.byName[vm.Name]; ok {
+		inv.unindex(old)
+	}
+	inv.index(vm)
+}
+
+// drop removes the VM from the indexes. Must be called with mu held.
+func (inv *inventory) drop(name string) {
+	if old, ok := inv.byName[name]; ok {
+		inv.unindex(old)
+		delete(inv.byName, name)
+	}
+}
+
+func (inv *inventory) put(vm *pb.VM) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.gen++
+	inv.updated[vm.Name] = inv.gen
+	inv.store(vm)
+}
+
+func (inv *inventory) remove(name string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.gen++
+	inv.updated[name] = inv.gen
+	inv.drop(name)
+}
+
+// generation returns the generation of the latest update.
+func (inv *inventory) generation() uint64 {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	return inv.gen
+}
+
+// merge applies a listing of the domains taken after generation since. VMs
+// put or removed after that keep their newer state, and so do the skipped
+// ones. VMs missing from a complete listing are dropped.
+func (inv *inventory) merge(l domainListing, since uint64) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	listed := map[string]bool{}
+	for _, vm := range l.vms {
+		listed[vm.Name] = true
+		if inv.updated[vm.Name] > since {
+			continue
+		}
+		inv.store(vm)
+	}
+
+	if l.complete {
+		for name := range inv.byName {
+			if !listed[name] && !l.skipped[name] && inv.updated[name] <= since {
+				inv.drop(name)
+			}
+		}
+	}
+
+	// older updates can't conflict with later listings anymore.
+	for name, gen := range inv.updated {
+		if gen <= since {
+			delete(inv.updated, name)
+		}
+	}
+}
+
+func (inv *inventory) get(name string) (*pb.VM, bool) {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	vm, ok := inv.byName[name]
+	return vm, ok
+}
+
+func (inv *inventory) findByIP(ip string) (*pb.VM, bool) {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	vm, ok := inv.byIP[ip]
+	return vm, ok
+}
+
+func (inv *inventory) findByMAC(mac string) (*pb.VM, bool) {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	vm, ok := inv.byMAC[strings.ToLower(mac)]
+	return vm, ok
+}
+
+// list returns all the VMs ordered by name.
+func (inv *inventory) list() []*pb.VM {
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+
+	vms := make([]*pb.VM, 0, len(inv.byName))
+	for _, vm := range inv.byName {
+		vms = append(vms, vm)
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+	return vms
+}
+
+// refreshInventory reloads a single VM from libvirt, dropping it from the
+// inventory if the domain is gone.
+func (s Server) refreshInventory(ctx context.Context, name string) {
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if grpc.Code(err) == codes.NotFound {
+		s.inventory.remove(name)
+		return
+	}
+	if err != nil {
+		glog.Warningf("failed to refresh %s in inventory: %v", name, err)
+		return
+	}
+
+	vm, err := describeDomain(ctx, *d)
+	if err != nil {
+		glog.Warningf("failed to refresh %s in inventory: %v", name, err)
+		return
+	}
+	s.inventory.put(vm)
+}
+
+// SyncInventory reloads all the VMs from libvirt. Domains that fail to be
+// described keep their current entry.
+func (s Server) SyncInventory(ctx context.Context) error {
+	since := s.inventory.generation()
+
+	listing, err := describeAllDomains(ctx, s.conn)
+	if err != nil {
+		return err
+	}
+
+	s.inventory.merge(listing, since)
+	return nil
+}
+
+// RunInventoryResync calls SyncInventory every interval until ctx is done, to
+// catch up with any changes the domain events missed.
+func (s Server) RunInventoryResync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.SyncInventory(ctx)
+		if err != nil {
+			glog.Errorf("inventory resync failed: %v", err)
+		}
+	}
+}
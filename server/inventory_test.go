@@ -0,0 +1,116 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+func inventoryNames(inv *inventory) []string {
+	names := []string{}
+	for _, vm := range inv.list() {
+		names = append(names, vm.Name+"="+vm.Ip)
+	}
+	return names
+}
+
+func TestInventoryMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  func(inv *inventory)
+		after   func(inv *inventory)
+		listing domainListing
+		want    []string
+	}{
+		{
+			name:    "replaces listed and drops missing",
+			before:  func(inv *inventory) { inv.put(&pb.VM{Name: "a", Ip: "1"}); inv.put(&pb.VM{Name: "b", Ip: "2"}) },
+			listing: domainListing{vms: []*pb.VM{{Name: "a", Ip: "3"}}, complete: true},
+			want:    []string{"a=3"},
+		},
+		{
+			name:    "keeps vms put during the listing",
+			after:   func(inv *inventory) { inv.put(&pb.VM{Name: "new", Ip: "1"}) },
+			listing: domainListing{vms: []*pb.VM{}, complete: true},
+			want:    []string{"new=1"},
+		},
+		{
+			name:    "keeps vms removed during the listing removed",
+			before:  func(inv *inventory) { inv.put(&pb.VM{Name: "gone", Ip: "1"}) },
+			after:   func(inv *inventory) { inv.remove("gone") },
+			listing: domainListing{vms: []*pb.VM{{Name: "gone", Ip: "1"}}, complete: true},
+			want:    []string{},
+		},
+		{
+			name:    "newer put wins over the listing",
+			before:  func(inv *inventory) { inv.put(&pb.VM{Name: "a", Ip: "1"}) },
+			after:   func(inv *inventory) { inv.put(&pb.VM{Name: "a", Ip: "2"}) },
+			listing: domainListing{vms: []*pb.VM{{Name: "a", Ip: "1"}}, complete: true},
+			want:    []string{"a=2"},
+		},
+		{
+			name:    "keeps skipped vms",
+			before:  func(inv *inventory) { inv.put(&pb.VM{Name: "a", Ip: "1"}); inv.put(&pb.VM{Name: "b", Ip: "2"}) },
+			listing: domainListing{vms: []*pb.VM{}, skipped: map[string]bool{"b": true}, complete: true},
+			want:    []string{"b=2"},
+		},
+		{
+			name:    "incomplete listing drops nothing",
+			before:  func(inv *inventory) { inv.put(&pb.VM{Name: "a", Ip: "1"}) },
+			listing: domainListing{vms: []*pb.VM{{Name: "c", Ip: "3"}}},
+			want:    []string{"a=1", "c=3"},
+		},
+	}
+
+	for _, tt := range tests {
+		inv := newInventory()
+		if tt.before != nil {
+			tt.before(inv)
+		}
+		since := inv.generation()
+		if tt.after != nil {
+			tt.after(inv)
+		}
+		inv.merge(tt.listing, since)
+
+		if got := inventoryNames(inv); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: inventory is %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInventoryMergeForgetsOldUpdates(t *testing.T) {
+	inv := newInventory()
+	inv.put(&pb.VM{Name: "a", Ip: "1"})
+	inv.remove("b")
+
+	inv.merge(domainListing{vms: []*pb.VM{{Name: "a", Ip: "1"}}, complete: true}, inv.generation())
+	if len(inv.updated) != 0 {
+		t.Errorf("updates older than the listing are still tracked: %v", inv.updated)
+	}
+
+	// the next listing isn't held back by updates it already saw.
+	inv.merge(domainListing{vms: []*pb.VM{{Name: "a", Ip: "2"}, {Name: "b", Ip: "3"}}, complete: true}, inv.generation())
+	if got, want := inventoryNames(inv), []string{"a=2", "b=3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inventory is %v, want %v", got, want)
+	}
+}
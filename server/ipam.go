@@ -0,0 +1,310 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// IPAllocator manages ip addresses of a single subnet.
+type IPAllocator interface {
+	// Allocate reserves the first free address for the owner.
+	Allocate(owner string) (net.IP, error)
+	// Reserve marks the given address as used by the owner.
+	Reserve(ip net.IP, owner string) error
+	// Release returns the address to the pool.
+	Release(ip net.IP) error
+	// Allocations returns all the allocated addresses with their owners.
+	Allocations() map[string]string
+}
+
+// IPRange is an inclusive range of ip addresses.
+type IPRange struct {
+	First net.IP
+	Last  net.IP
+}
+
+// Contains checks if the address is in range.
+func (r IPRange) Contains(ip net.IP) bool {
+	i := ipToInt(ip)
+	return ipToInt(r.First).Cmp(i) <= 0 && i.Cmp(ipToInt(r.Last)) <= 0
+}
+
+// ParseIPRanges parses a comma-separated list of addresses and address ranges,
+// e.g. "10.0.0.1,10.0.0.240-10.0.0.254".
+func ParseIPRanges(spec string) ([]IPRange, error) {
+	ranges := []IPRange{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		first := net.ParseIP(strings.TrimSpace(bounds[0]))
+		if first == nil {
+			return nil, fmt.Errorf("invalid ip address in range %q", part)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last = net.ParseIP(strings.TrimSpace(bounds[1]))
+			if last == nil {
+				return nil, fmt.Errorf("invalid ip address in range %q", part)
+			}
+		}
+		if ipToInt(first).Cmp(ipToInt(last)) > 0 {
+			return nil, fmt.Errorf("range %q ends before it starts", part)
+		}
+
+		ranges = append(ranges, IPRange{First: first, Last: last})
+	}
+	return ranges, nil
+}
+
+func ipToInt(ip net.IP) *big.Int {
+	if v4 := ip.To4(); v4 != nil {
+		return new(big.Int).SetBytes(v4)
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+func intToIP(i *big.Int, size int) net.IP {
+	b := i.Bytes()
+	ip := make(net.IP, size)
+	copy(ip[size-len(b):], b)
+	return ip
+}
+
+// eui64Address derives the SLAAC address of the interface from its mac.
+func eui64Address(prefix *net.IPNet, mac net.HardwareAddr) (net.IP, error) {
+	if ones, bits := prefix.Mask.Size(); ones != 64 || bits != 128 {
+		return nil, fmt.Errorf("eui-64 addressing needs a /64 ipv6 prefix, got %v", prefix)
+	}
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("eui-64 addressing needs a 48-bit mac, got %v", mac)
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, prefix.IP.To16()[:8])
+	ip[8] = mac[0] ^ 0x02
+	ip[9] = mac[1]
+	ip[10] = mac[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	ip[13] = mac[3]
+	ip[14] = mac[4]
+	ip[15] = mac[5]
+	return ip, nil
+}
+
+// allocatedError is returned by Reserve for addresses of another owner.
+type allocatedError struct {
+	ip    net.IP
+	owner string
+}
+
+func (e allocatedError) Error() string {
+	return fmt.Sprintf("%v is already allocated to %s", e.ip, e.owner)
+}
+
+type ipamState struct {
+	Allocations map[string]string `json:"allocations"`
+}
+
+// sequentialAllocator hands out the lowest free address of the subnet. The
+// allocations are persisted to a state file, if one is configured.
+type sequentialAllocator struct {
+	mu sync.Mutex
+
+	subnet    *net.IPNet
+	size      int
+	first     *big.Int
+	last      *big.Int
+	reserved  []IPRange
+	statePath string
+
+	allocated map[string]string
+}
+
+// NewIPAllocator creates an allocator for the subnet that never hands out
+// addresses from the reserved ranges. If statePath is not empty, allocations
+// are loaded from and saved to that file.
+func NewIPAllocator(subnet *net.IPNet, reserved []IPRange, statePath string) (IPAllocator, error) {
+	ones, bits := subnet.Mask.Size()
+	size := bits / 8
+	base := ipToInt(subnet.IP)
+	hostBits := uint(bits - ones)
+
+	first := new(big.Int).Set(base)
+	last := new(big.Int).Add(base, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), hostBits), big.NewInt(1)))
+	if hostBits > 1 {
+		// skip the network address, and the broadcast one for ipv4.
+		first.Add(first, big.NewInt(1))
+		if size == net.IPv4len {
+			last.Sub(last, big.NewInt(1))
+		}
+	}
+
+	a := &sequentialAllocator{
+		subnet:    subnet,
+		size:      size,
+		first:     first,
+		last:      last,
+		reserved:  reserved,
+		statePath: statePath,
+		allocated: map[string]string{},
+	}
+
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *sequentialAllocator) load() error {
+	if a.statePath == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(a.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ipam state: %v", err)
+	}
+
+	state := ipamState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse ipam state %s: %v", a.statePath, err)
+	}
+	if state.Allocations != nil {
+		a.allocated = state.Allocations
+	}
+	return nil
+}
+
+// save writes the state file atomically. Must be called with mu held.
+func (a *sequentialAllocator) save() error {
+	if a.statePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(ipamState{Allocations: a.allocated}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(a.statePath, data); err != nil {
+		return fmt.Errorf("failed to save ipam state: %v", err)
+	}
+	return nil
+}
+
+func (a *sequentialAllocator) isReserved(ip net.IP) bool {
+	for _, r := range a.reserved {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *sequentialAllocator) Allocate(owner string) (net.IP, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	one := big.NewInt(1)
+	for i := new(big.Int).Set(a.first); i.Cmp(a.last) <= 0; i.Add(i, one) {
+		ip := intToIP(i, a.size)
+		if _, ok := a.allocated[ip.String()]; ok || a.isReserved(ip) {
+			continue
+		}
+
+		a.allocated[ip.String()] = owner
+		if err := a.save(); err != nil {
+			delete(a.allocated, ip.String())
+			return nil, err
+		}
+		return ip, nil
+	}
+
+	return nil, fmt.Errorf("no free addresses left in %v", a.subnet)
+}
+
+func (a *sequentialAllocator) Reserve(ip net.IP, owner string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.subnet.Contains(ip) {
+		return fmt.Errorf("%v is not in %v", ip, a.subnet)
+	}
+
+	key := ip.String()
+	if current, ok := a.allocated[key]; ok {
+		if current == owner {
+			return nil
+		}
+		return allocatedError{ip: ip, owner: current}
+	}
+
+	a.allocated[key] = owner
+	if err := a.save(); err != nil {
+		delete(a.allocated, key)
+		return err
+	}
+	return nil
+}
+
+func (a *sequentialAllocator) Release(ip net.IP) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := ip.String()
+	owner, ok := a.allocated[key]
+	if !ok {
+		return nil
+	}
+
+	delete(a.allocated, key)
+	if err := a.save(); err != nil {
+		a.allocated[key] = owner
+		return err
+	}
+	return nil
+}
+
+func (a *sequentialAllocator) Allocations() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	allocations := make(map[string]string, len(a.allocated))
+	for ip, owner := range a.allocated {
+		allocations[ip] = owner
+	}
+	return allocations
+}
@@ -0,0 +1,255 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func mustParseCIDR(t *testing.T, cidr string) *net.IPNet {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", cidr, err)
+	}
+	return subnet
+}
+
+func TestParseIPRanges(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "", want: []string{}},
+		{spec: "10.0.0.1", want: []string{"10.0.0.1-10.0.0.1"}},
+		{spec: "10.0.0.1, 10.0.0.240-10.0.0.254", want: []string{"10.0.0.1-10.0.0.1", "10.0.0.240-10.0.0.254"}},
+		{spec: "fd00::1-fd00::ff", want: []string{"fd00::1-fd00::ff"}},
+		{spec: "10.0.0.1,,", want: []string{"10.0.0.1-10.0.0.1"}},
+		{spec: "10.0.0.300", wantErr: true},
+		{spec: "10.0.0.1-", wantErr: true},
+		{spec: "10.0.0.9-10.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ranges, err := ParseIPRanges(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseIPRanges(%q) succeeded, want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseIPRanges(%q) failed: %v", tt.spec, err)
+			continue
+		}
+
+		got := []string{}
+		for _, r := range ranges {
+			got = append(got, r.First.String()+"-"+r.Last.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseIPRanges(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestIPAllocatorAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		subnet   string
+		reserved string
+		want     []string
+	}{
+		{
+			name:   "skips network and broadcast",
+			subnet: "10.0.0.0/29",
+			want:   []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"},
+		},
+		{
+			name:     "skips reserved ranges",
+			subnet:   "10.0.0.0/29",
+			reserved: "10.0.0.1,10.0.0.3-10.0.0.4",
+			want:     []string{"10.0.0.2", "10.0.0.5", "10.0.0.6"},
+		},
+		{
+			name:   "point to point",
+			subnet: "10.0.0.0/31",
+			want:   []string{"10.0.0.0", "10.0.0.1"},
+		},
+		{
+			name:   "ipv6 has no broadcast",
+			subnet: "fd00::/126",
+			want:   []string{"fd00::1", "fd00::2", "fd00::3"},
+		},
+	}
+
+	for _, tt := range tests {
+		reserved, err := ParseIPRanges(tt.reserved)
+		if err != nil {
+			t.Fatalf("%s: failed to parse reserved ranges: %v", tt.name, err)
+		}
+		a, err := NewIPAllocator(mustParseCIDR(t, tt.subnet), reserved, "")
+		if err != nil {
+			t.Fatalf("%s: NewIPAllocator failed: %v", tt.name, err)
+		}
+
+		got := []string{}
+		for range tt.want {
+			ip, err := a.Allocate("vm")
+			if err != nil {
+				t.Errorf("%s: Allocate failed after %v: %v", tt.name, got, err)
+				break
+			}
+			got = append(got, ip.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: allocated %v, want %v", tt.name, got, tt.want)
+		}
+
+		if ip, err := a.Allocate("vm"); err == nil {
+			t.Errorf("%s: Allocate of an exhausted subnet returned %v", tt.name, ip)
+		}
+	}
+}
+
+func TestIPAllocatorReleaseReuse(t *testing.T) {
+	a, err := NewIPAllocator(mustParseCIDR(t, "10.0.0.0/29"), nil, "")
+	if err != nil {
+		t.Fatalf("NewIPAllocator failed: %v", err)
+	}
+
+	for _, owner := range []string{"vm1", "vm2", "vm3"} {
+		if _, err := a.Allocate(owner); err != nil {
+			t.Fatalf("Allocate failed: %v", err)
+		}
+	}
+
+	if err := a.Release(net.ParseIP("10.0.0.2")); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := a.Release(net.ParseIP("10.0.0.5")); err != nil {
+		t.Errorf("Release of a free address failed: %v", err)
+	}
+
+	ip, err := a.Allocate("vm4")
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	if ip.String() != "10.0.0.2" {
+		t.Errorf("Allocate after release returned %v, want the released 10.0.0.2", ip)
+	}
+
+	want := map[string]string{"10.0.0.1": "vm1", "10.0.0.2": "vm4", "10.0.0.3": "vm3"}
+	if got := a.Allocations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Allocations() = %v, want %v", got, want)
+	}
+}
+
+func TestIPAllocatorReserve(t *testing.T) {
+	a, err := NewIPAllocator(mustParseCIDR(t, "10.0.0.0/24"), nil, "")
+	if err != nil {
+		t.Fatalf("NewIPAllocator failed: %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		owner   string
+		wantErr bool
+	}{
+		{ip: "10.0.0.10", owner: "vm1"},
+		{ip: "10.0.0.10", owner: "vm1"},
+		{ip: "10.0.0.10", owner: "vm2", wantErr: true},
+		{ip: "10.0.1.10", owner: "vm2", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := a.Reserve(net.ParseIP(tt.ip), tt.owner)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Reserve(%s, %s) = %v, want error: %v", tt.ip, tt.owner, err, tt.wantErr)
+		}
+	}
+
+	if _, ok := a.Reserve(net.ParseIP("10.0.0.10"), "vm2").(allocatedError); !ok {
+		t.Errorf("Reserve of an address of another owner didn't return allocatedError")
+	}
+
+	// reserved addresses are skipped by Allocate.
+	if err := a.Reserve(net.ParseIP("10.0.0.1"), "vm3"); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	ip, err := a.Allocate("vm4")
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	if ip.String() != "10.0.0.2" {
+		t.Errorf("Allocate returned %v, want 10.0.0.2", ip)
+	}
+}
+
+func TestIPAllocatorPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ipam")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statePath := filepath.Join(dir, "state.json")
+	subnet := mustParseCIDR(t, "10.0.0.0/24")
+
+	a, err := NewIPAllocator(subnet, nil, statePath)
+	if err != nil {
+		t.Fatalf("NewIPAllocator failed: %v", err)
+	}
+	for _, owner := range []string{"vm1", "vm2"} {
+		if _, err := a.Allocate(owner); err != nil {
+			t.Fatalf("Allocate failed: %v", err)
+		}
+	}
+	if err := a.Release(net.ParseIP("10.0.0.1")); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	reloaded, err := NewIPAllocator(subnet, nil, statePath)
+	if err != nil {
+		t.Fatalf("NewIPAllocator failed to reload the state: %v", err)
+	}
+	want := map[string]string{"10.0.0.2": "vm2"}
+	if got := reloaded.Allocations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded Allocations() = %v, want %v", got, want)
+	}
+
+	ip, err := reloaded.Allocate("vm3")
+	if err != nil {
+		t.Fatalf("Allocate failed: %v", err)
+	}
+	if ip.String() != "10.0.0.1" {
+		t.Errorf("Allocate after reload returned %v, want 10.0.0.1", ip)
+	}
+
+	if err := ioutil.WriteFile(statePath, []byte("{"), 0600); err != nil {
+		t.Fatalf("failed to corrupt the state: %v", err)
+	}
+	if _, err := NewIPAllocator(subnet, nil, statePath); err == nil {
+		t.Errorf("NewIPAllocator succeeded with a corrupt state file")
+	}
+}
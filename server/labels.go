@@ -0,0 +1,164 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+const (
+	maxLabels           = 64
+	maxLabelKeyLength   = 63
+	maxLabelValueLength = 255
+)
+
+var labelKeyRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]*[a-zA-Z0-9])?$`)
+
+// validateLabels checks that labels can be stored and selected on. Values
+// can't contain the separators used by selectors.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("too many labels, at most %d allowed", maxLabels)
+	}
+	for k, v := range labels {
+		if len(k) > maxLabelKeyLength || !labelKeyRe.MatchString(k) {
+			return fmt.Errorf("invalid label key %q", k)
+		}
+		if len(v) > maxLabelValueLength || strings.ContainsAny(v, ",=") {
+			return fmt.Errorf("invalid value of label %s", k)
+		}
+	}
+	return nil
+}
+
+// labelRequirement is a single term of a label selector.
+type labelRequirement struct {
+	key   string
+	value string
+	// exists only checks for the key presence.
+	exists bool
+	negate bool
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	if r.exists {
+		return ok != r.negate
+	}
+	return (ok && v == r.value) != r.negate
+}
+
+// labelSelector is a list of requirements that must all match.
+type labelSelector []labelRequirement
+
+// parseLabelSelector parses a comma-separated list of key=value, key!=value,
+// key and !key requirements.
+func parseLabelSelector(spec string) (labelSelector, error) {
+	sel := labelSelector{}
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		r := labelRequirement{}
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r.key, r.value, r.negate = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			r.key, r.value = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		case strings.HasPrefix(term, "!"):
+			r.key, r.exists, r.negate = strings.TrimSpace(term[1:]), true, true
+		default:
+			r.key, r.exists = term, true
+		}
+
+		if !labelKeyRe.MatchString(r.key) {
+			return nil, fmt.Errorf("invalid label selector term %q", term)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+func (sel labelSelector) matches(labels map[string]string) bool {
+	for _, r := range sel {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateLabels is GRPC handler for UpdateLabels API.
+func (s Server) UpdateLabels(ctx context.Context, in *pb.UpdateLabelsRequest) (*pb.VM, error) {
+	name := in.GetName()
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+	defer s.locks.lock(name)()
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	dom, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+
+	md := dom.Metadata.VMRegistry
+	labels := md.labelMap()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range in.GetSet() {
+		labels[k] = v
+	}
+	for _, k := range in.GetRemove() {
+		delete(labels, k)
+	}
+	if err := validateLabels(labels); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	md.setLabels(labels)
+
+	err = traceDomainSetMetadata(ctx, *d, md)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, err := describeDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	s.inventory.put(vm)
+	return vm, nil
+}
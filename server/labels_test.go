@@ -0,0 +1,105 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	web := map[string]string{"role": "web", "env": "prod"}
+	db := map[string]string{"role": "db"}
+	none := map[string]string{}
+
+	tests := []struct {
+		spec    string
+		matches []map[string]string
+		misses  []map[string]string
+		wantErr bool
+	}{
+		{spec: "", matches: []map[string]string{web, db, none}},
+		{spec: "role=web", matches: []map[string]string{web}, misses: []map[string]string{db, none}},
+		{spec: " role = web ", matches: []map[string]string{web}, misses: []map[string]string{db}},
+		{spec: "role!=web", matches: []map[string]string{db, none}, misses: []map[string]string{web}},
+		{spec: "env", matches: []map[string]string{web}, misses: []map[string]string{db, none}},
+		{spec: "!env", matches: []map[string]string{db, none}, misses: []map[string]string{web}},
+		{spec: "role,!env", matches: []map[string]string{db}, misses: []map[string]string{web, none}},
+		{spec: "role=web,env=prod,", matches: []map[string]string{web}, misses: []map[string]string{db}},
+		{spec: "role=", matches: []map[string]string{{"role": ""}}, misses: []map[string]string{web, none}},
+		{spec: "=web", wantErr: true},
+		{spec: "!", wantErr: true},
+		{spec: "role=web,bad key", wantErr: true},
+		{spec: "-role", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		sel, err := parseLabelSelector(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLabelSelector(%q) succeeded, want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseLabelSelector(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		for _, labels := range tt.matches {
+			if !sel.matches(labels) {
+				t.Errorf("selector %q doesn't match %v", tt.spec, labels)
+			}
+		}
+		for _, labels := range tt.misses {
+			if sel.matches(labels) {
+				t.Errorf("selector %q matches %v", tt.spec, labels)
+			}
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= maxLabels; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		wantErr bool
+	}{
+		{name: "empty", labels: map[string]string{}},
+		{name: "valid", labels: map[string]string{"role": "web", "example.com/team": "infra", "a": ""}},
+		{name: "long key", labels: map[string]string{strings.Repeat("k", maxLabelKeyLength+1): "v"}, wantErr: true},
+		{name: "long value", labels: map[string]string{"k": strings.Repeat("v", maxLabelValueLength+1)}, wantErr: true},
+		{name: "key with space", labels: map[string]string{"a b": "v"}, wantErr: true},
+		{name: "key ending with dot", labels: map[string]string{"a.": "v"}, wantErr: true},
+		{name: "value with comma", labels: map[string]string{"k": "a,b"}, wantErr: true},
+		{name: "value with equals", labels: map[string]string{"k": "a=b"}, wantErr: true},
+		{name: "too many", labels: tooMany, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateLabels(tt.labels)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateLabels() = %v, want error: %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -19,6 +19,10 @@ limitations under the License.
 package server
 
 import (
+	"encoding/xml"
+	"sort"
+	"strings"
+
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -36,8 +40,43 @@ type libvirtInterface struct {
 	Mac libvirtMac `xml:"mac"`
 }
 
+type libvirtDiskSource struct {
+	Dev  string `xml:"dev,attr"`
+	File string `xml:"file,attr"`
+}
+
+type libvirtDiskTarget struct {
+	Dev string `xml:"dev,attr"`
+	Bus string `xml:"bus,attr,omitempty"`
+}
+
+type libvirtDisk struct {
+	Device string            `xml:"device,attr"`
+	Source libvirtDiskSource `xml:"source"`
+	Target libvirtDiskTarget `xml:"target"`
+}
+
 type libvirtDevice struct {
 	Interface []libvirtInterface `xml:"interface"`
+	Disk      []libvirtDisk      `xml:"disk"`
+}
+
+type libvirtVCPU struct {
+	Current uint32 `xml:"current,attr"`
+	Max     uint32 `xml:",chardata"`
+}
+
+// count returns the number of vcpus the domain runs with.
+func (v libvirtVCPU) count() uint32 {
+	if v.Current != 0 {
+		return v.Current
+	}
+	return v.Max
+}
+
+type libvirtMemory struct {
+	Unit  string `xml:"unit,attr"`
+	Value uint64 `xml:",chardata"`
 }
 
 type libvirtMetadata struct {
@@ -45,12 +84,133 @@ type libvirtMetadata struct {
 }
 
 type libvirtDomain struct {
-	Devices  libvirtDevice   `xml:"devices"`
-	Metadata libvirtMetadata `xml:"metadata"`
+	Name          string          `xml:"name"`
+	Memory        libvirtMemory   `xml:"memory"`
+	CurrentMemory libvirtMemory   `xml:"currentMemory"`
+	VCPU          libvirtVCPU     `xml:"vcpu"`
+	Devices       libvirtDevice   `xml:"devices"`
+	Metadata      libvirtMetadata `xml:"metadata"`
+}
+
+// memoryBytes returns the memory the domain runs with, which is less than its
+// maximum memory if ballooned.
+func (d libvirtDomain) memoryBytes() uint64 {
+	if current := d.CurrentMemory.bytes(); current != 0 {
+		return current
+	}
+	return d.Memory.bytes()
 }
 
+// vmMetadataNamespace is the xml namespace of the vmregistry element in
+// domain metadata.
+const vmMetadataNamespace = "https://github.com/google/vmregistry"
+
 type vmMetadata struct {
-	IP string `xml:"ip"`
+	IP          string `xml:"ip"`
+	SourceImage string `xml:"source_image,omitempty"`
+	Size        uint64 `xml:"size,omitempty"`
+	Created     int64  `xml:"created,omitempty"`
+	Network     string `xml:"network,omitempty"`
+	IPv6        string `xml:"ipv6,omitempty"`
+	Flavor      string `xml:"flavor,omitempty"`
+	Template    string `xml:"template,omitempty"`
+	// RequestID is the request id of the create, to recognize its retries
+	// once the operation is forgotten.
+	RequestID string `xml:"request_id,omitempty"`
+
+	Labels    []vmLabel    `xml:"labels>label,omitempty"`
+	Disks     []vmDisk     `xml:"disks>disk,omitempty"`
+	Snapshots []vmSnapshot `xml:"snapshots>snapshot,omitempty"`
+}
+
+// vmSnapshot is a snapshot taken with CreateSnapshot.
+type vmSnapshot struct {
+	Name     string   `xml:"name,attr"`
+	Created  int64    `xml:"created,attr"`
+	Quiesced bool     `xml:"quiesced,attr,omitempty"`
+	Disks    []string `xml:"disk,omitempty"`
+}
+
+// vmDisk is a data disk attached with AttachDisk.
+type vmDisk struct {
+	Name   string `xml:"name,attr"`
+	Size   uint64 `xml:"size,attr"`
+	Target string `xml:"target,attr"`
+	Retain bool   `xml:"retain,attr,omitempty"`
+	// Detaching is set from the detach request until the guest released
+	// the disk and its volume is removed.
+	Detaching bool `xml:"detaching,attr,omitempty"`
+}
+
+type vmLabel struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// labelMap returns the labels as a map.
+func (m vmMetadata) labelMap() map[string]string {
+	if len(m.Labels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(m.Labels))
+	for _, l := range m.Labels {
+		labels[l.Key] = l.Value
+	}
+	return labels
+}
+
+// setLabels replaces the labels, keeping them sorted by key.
+func (m *vmMetadata) setLabels(labels map[string]string) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	m.Labels = nil
+	for _, k := range keys {
+		m.Labels = append(m.Labels, vmLabel{Key: k, Value: labels[k]})
+	}
+}
+
+// marshal renders metadata as a namespaced element suitable for domain xml.
+func (m vmMetadata) marshal() (string, error) {
+	b, err := xml.Marshal(struct {
+		XMLName xml.Name
+		vmMetadata
+	}{
+		XMLName:    xml.Name{Space: vmMetadataNamespace, Local: "vmregistry"},
+		vmMetadata: m,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// bytes returns the memory size in bytes, honoring the libvirt unit.
+func (m libvirtMemory) bytes() uint64 {
+	switch strings.ToLower(m.Unit) {
+	case "b", "bytes":
+		return m.Value
+	case "kb":
+		return m.Value * 1000
+	case "", "k", "kib":
+		return m.Value << 10
+	case "mb":
+		return m.Value * 1000 * 1000
+	case "m", "mib":
+		return m.Value << 20
+	case "gb":
+		return m.Value * 1000 * 1000 * 1000
+	case "g", "gib":
+		return m.Value << 30
+	case "tb":
+		return m.Value * 1000 * 1000 * 1000 * 1000
+	case "t", "tib":
+		return m.Value << 40
+	}
+	return 0
 }
 
 func traceListAllDomains(ctx context.Context, conn *libvirt.Connect) ([]libvirt.Domain, error) {
@@ -80,6 +240,9 @@ func traceGetDomainByName(ctx context.Context, conn *libvirt.Connect, name strin
 
 	if err != nil {
 		sp.SetTag("error", true)
+		if lerr, ok := err.(libvirt.Error); ok && lerr.Code == libvirt.ERR_NO_DOMAIN {
+			return nil, grpc.Errorf(codes.NotFound, "domain %s not found", name)
+		}
 		return nil, grpc.Errorf(codes.Unavailable, "failed to get domain %s: %v", name, err)
 	}
 
@@ -101,13 +264,13 @@ func traceDomainGetName(ctx context.Context, dom libvirt.Domain) (string, error)
 	return name, nil
 }
 
-func traceDomainGetXMLDesc(ctx context.Context, dom libvirt.Domain) (string, error) {
+func traceDomainGetXMLDesc(ctx context.Context, dom libvirt.Domain, flags libvirt.DomainXMLFlags) (string, error) {
 	sp, _ := opentracing.StartSpanFromContext(ctx, "libvirt.domain.GetXMLDesc")
 	sp.SetTag("component", "libvirt")
 	sp.SetTag("span.kind", "client")
 	defer sp.Finish()
 
-	xml, err := dom.GetXMLDesc(libvirt.DOMAIN_XML_INACTIVE)
+	xml, err := dom.GetXMLDesc(flags)
 
 	if err != nil {
 		sp.SetTag("error", true)
@@ -115,3 +278,101 @@ func traceDomainGetXMLDesc(ctx context.Context, dom libvirt.Domain) (string, err
 	}
 	return xml, nil
 }
+
+func traceDomainGetState(ctx context.Context, dom libvirt.Domain) (libvirt.DomainState, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "libvirt.domain.GetState")
+	sp.SetTag("component", "libvirt")
+	sp.SetTag("span.kind", "client")
+	defer sp.Finish()
+
+	state, _, err := dom.GetState()
+
+	if err != nil {
+		sp.SetTag("error", true)
+		return libvirt.DOMAIN_NOSTATE, grpc.Errorf(codes.Unavailable, "failed to get domain state: %v", err)
+	}
+	return state, nil
+}
+
+// traceDomainSetMetadata replaces the vmregistry metadata element of the
+// domain config, and of the running domain if it's active.
+func traceDomainSetMetadata(ctx context.Context, dom libvirt.Domain, md vmMetadata) error {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "libvirt.domain.SetMetadata")
+	sp.SetTag("component", "libvirt")
+	sp.SetTag("span.kind", "client")
+	defer sp.Finish()
+
+	content, err := md.marshal()
+	if err != nil {
+		sp.SetTag("error", true)
+		return grpc.Errorf(codes.Internal, "failed to render vm metadata: %v", err)
+	}
+
+	flags := libvirt.DOMAIN_AFFECT_CONFIG
+	if active, err := dom.IsActive(); err == nil && active {
+		flags |= libvirt.DOMAIN_AFFECT_LIVE
+	}
+
+	err = dom.SetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, content, "vmregistry", vmMetadataNamespace, flags)
+
+	if err != nil {
+		sp.SetTag("error", true)
+		return grpc.Errorf(libvirtErrorCode(err), "failed to set domain metadata: %v", err)
+	}
+	return nil
+}
+
+func traceDomainBlockResize(ctx context.Context, dom libvirt.Domain, disk string, size uint64) error {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "libvirt.domain.BlockResize")
+	sp.SetTag("component", "libvirt")
+	sp.SetTag("span.kind", "client")
+	defer sp.Finish()
+
+	err := dom.BlockResize(disk, size, libvirt.DOMAIN_BLOCK_RESIZE_BYTES)
+
+	if err != nil {
+		sp.SetTag("error", true)
+		return grpc.Errorf(libvirtErrorCode(err), "failed to resize block device %s: %v", disk, err)
+	}
+	return nil
+}
+
+// traceDomainAction runs a libvirt call that changes domain state under its
+// own span.
+func traceDomainAction(ctx context.Context, op string, action func() error) error {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "libvirt.domain."+op)
+	sp.SetTag("component", "libvirt")
+	sp.SetTag("span.kind", "client")
+	defer sp.Finish()
+
+	err := action()
+
+	if err != nil {
+		sp.SetTag("error", true)
+		return err
+	}
+	return nil
+}
+
+// libvirtErrorCode maps a libvirt error to the closest grpc code.
+func libvirtErrorCode(err error) codes.Code {
+	switch err {
+	case context.DeadlineExceeded:
+		return codes.DeadlineExceeded
+	case context.Canceled:
+		return codes.Canceled
+	}
+
+	lerr, ok := err.(libvirt.Error)
+	if !ok {
+		return codes.Internal
+	}
+
+	switch lerr.Code {
+	case libvirt.ERR_NO_DOMAIN:
+		return codes.NotFound
+	case libvirt.ERR_OPERATION_INVALID:
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
@@ -0,0 +1,306 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// maxPageSize caps the number of VMs in a single List reply.
+const maxPageSize = 1000
+
+// vmFilter selects the VMs returned by List.
+type vmFilter struct {
+	name    string
+	glob    bool
+	states  map[pb.VM_State]bool
+	network string
+	labels  labelSelector
+}
+
+func newVMFilter(f *pb.ListFilter) (*vmFilter, error) {
+	if f == nil {
+		return &vmFilter{}, nil
+	}
+
+	labels, err := parseLabelSelector(f.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &vmFilter{
+		name:    f.Name,
+		glob:    strings.ContainsAny(f.Name, "*?["),
+		network: f.Network,
+		labels:  labels,
+	}
+	if filter.glob {
+		if _, err := path.Match(f.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q", f.Name)
+		}
+	}
+	if len(f.States) > 0 {
+		filter.states = map[pb.VM_State]bool{}
+		for _, state := range f.States {
+			filter.states[state] = true
+		}
+	}
+	return filter, nil
+}
+
+func (f *vmFilter) matches(vm *pb.VM) bool {
+	if f.glob {
+		if ok, _ := path.Match(f.name, vm.Name); !ok {
+			return false
+		}
+	} else if !strings.HasPrefix(vm.Name, f.name) {
+		return false
+	}
+	if f.states != nil && !f.states[vm.State] {
+		return false
+	}
+	if f.network != "" && vm.Network != f.network {
+		return false
+	}
+	return f.labels.matches(vm.Labels)
+}
+
+// vmOrder sorts List results by one of the VM fields, then by name.
+type vmOrder struct {
+	field string
+	desc  bool
+}
+
+func parseOrderBy(spec string) (vmOrder, error) {
+	parts := strings.Fields(spec)
+	order := vmOrder{field: "name"}
+	if len(parts) > 0 {
+		order.field = parts[0]
+	}
+	if len(parts) > 1 {
+		if len(parts) > 2 || !strings.EqualFold(parts[1], "desc") {
+			return vmOrder{}, fmt.Errorf("invalid order %q", spec)
+		}
+		order.desc = true
+	}
+
+	switch order.field {
+	case "name", "created", "mem", "cores", "size", "state", "network", "ip":
+		return order, nil
+	}
+	return vmOrder{}, fmt.Errorf("can't order by %q", order.field)
+}
+
+func (o vmOrder) String() string {
+	if o.desc {
+		return o.field + " desc"
+	}
+	return o.field
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareIP(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA == nil && ipB == nil:
+		return 0
+	case ipA == nil:
+		return -1
+	case ipB == nil:
+		return 1
+	}
+	return ipToInt(ipA).Cmp(ipToInt(ipB))
+}
+
+func (o vmOrder) compare(a, b *pb.VM) int {
+	c := 0
+	switch o.field {
+	case "created":
+		c = compareUint(uint64(a.Created), uint64(b.Created))
+	case "mem":
+		c = compareUint(a.Mem, b.Mem)
+	case "cores":
+		c = compareUint(uint64(a.Cores), uint64(b.Cores))
+	case "size":
+		c = compareUint(a.Size, b.Size)
+	case "state":
+		c = compareUint(uint64(a.State), uint64(b.State))
+	case "network":
+		c = strings.Compare(a.Network, b.Network)
+	case "ip":
+		c = compareIP(a.Ip, b.Ip)
+	}
+	if c == 0 {
+		c = strings.Compare(a.Name, b.Name)
+	}
+	if o.desc {
+		return -c
+	}
+	return c
+}
+
+func (o vmOrder) less(a, b *pb.VM) bool {
+	return o.compare(a, b) < 0
+}
+
+// listPageToken is the position after the last VM of a page. It keeps the
+// fields the order uses, so that pages stay consistent when VMs come and go.
+type listPageToken struct {
+	Query  string `json:"q"`
+	Cursor []byte `json:"c"`
+}
+
+// listQuery identifies the filter and order a page token belongs to.
+func listQuery(req *pb.ListVMRequest, order vmOrder) string {
+	return proto.CompactTextString(listFilter(req)) + "|" + order.String()
+}
+
+// listFilter returns the filter of the request, with the deprecated top level
+// label selector folded into it.
+func listFilter(req *pb.ListVMRequest) *pb.ListFilter {
+	if req.GetLabelSelector() == "" {
+		return req.GetFilter()
+	}
+
+	f := &pb.ListFilter{}
+	if req.GetFilter() != nil {
+		f = proto.Clone(req.GetFilter()).(*pb.ListFilter)
+	}
+	if f.LabelSelector == "" {
+		f.LabelSelector = req.GetLabelSelector()
+	} else {
+		f.LabelSelector += "," + req.GetLabelSelector()
+	}
+	return f
+}
+
+func encodePageToken(query string, last *pb.VM) (string, error) {
+	cursor, err := proto.Marshal(&pb.VM{
+		Name:    last.Name,
+		Created: last.Created,
+		Mem:     last.Mem,
+		Cores:   last.Cores,
+		Size:    last.Size,
+		State:   last.State,
+		Network: last.Network,
+		Ip:      last.Ip,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(listPageToken{Query: query, Cursor: cursor})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodePageToken(token string, query string) (*pb.VM, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+
+	t := listPageToken{}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	if t.Query != query {
+		return nil, fmt.Errorf("page token doesn't match the filter and order")
+	}
+
+	cursor := &pb.VM{}
+	if err := proto.Unmarshal(t.Cursor, cursor); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	return cursor, nil
+}
+
+// List is GRPC handler for List API.
+func (s Server) List(ctx context.Context, req *pb.ListVMRequest) (*pb.ListVMReply, error) {
+	filter, err := newVMFilter(listFilter(req))
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	order, err := parseOrderBy(req.GetOrderBy())
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	pageSize := int(req.GetPageSize())
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	query := listQuery(req, order)
+	var cursor *pb.VM
+	if req.GetPageToken() != "" {
+		cursor, err = decodePageToken(req.GetPageToken(), query)
+		if err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+
+	vms := []*pb.VM{}
+	for _, vm := range s.inventory.list() {
+		if !filter.matches(vm) {
+			continue
+		}
+		if cursor != nil && !order.less(cursor, vm) {
+			continue
+		}
+		vms = append(vms, vm)
+	}
+	sort.Slice(vms, func(i, j int) bool { return order.less(vms[i], vms[j]) })
+
+	repl := &pb.ListVMReply{Vms: vms}
+	if pageSize > 0 && len(vms) > pageSize {
+		repl.Vms = vms[:pageSize]
+		repl.NextPageToken, err = encodePageToken(query, vms[pageSize-1])
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "failed to encode page token: %v", err)
+		}
+	}
+	return repl, nil
+}
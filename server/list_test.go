@@ -0,0 +1,259 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+func newListServer(vms ...*pb.VM) Server {
+	s := Server{inventory: newInventory()}
+	for _, vm := range vms {
+		s.inventory.put(vm)
+	}
+	return s
+}
+
+func testVMs() []*pb.VM {
+	return []*pb.VM{
+		{Name: "db1", Ip: "10.0.0.10", Mem: 8, Cores: 4, Created: 5, Network: "prod", Labels: map[string]string{"role": "db", "env": "prod"}},
+		{Name: "db2", Ip: "10.0.0.9", Mem: 8, Cores: 4, Created: 3, Network: "prod", Labels: map[string]string{"role": "db", "env": "staging"}},
+		{Name: "web1", Ip: "10.0.0.2", Mem: 2, Cores: 1, Created: 1, Network: "prod", Labels: map[string]string{"role": "web", "env": "prod"}},
+		{Name: "web2", Ip: "10.0.0.100", Mem: 2, Cores: 2, Created: 4, Network: "prod", Labels: map[string]string{"role": "web"}},
+		{Name: "web3", Ip: "10.0.1.1", Mem: 4, Cores: 2, Created: 2, Network: "dev", State: pb.VM_RUNNING},
+		{Name: "cache", Ip: "10.0.0.3", Mem: 16, Cores: 2, Created: 6, Network: "dev", State: pb.VM_RUNNING},
+		{Name: "build", Ip: "10.0.1.2", Mem: 4, Cores: 8, Created: 7, Network: "dev"},
+	}
+}
+
+func vmNames(vms []*pb.VM) []string {
+	names := []string{}
+	for _, vm := range vms {
+		names = append(names, vm.Name)
+	}
+	return names
+}
+
+// listAll follows the page tokens until the last page.
+func listAll(t *testing.T, s Server, req *pb.ListVMRequest) []string {
+	names := []string{}
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatalf("too many pages for %v", req)
+		}
+		repl, err := s.List(context.Background(), req)
+		if err != nil {
+			t.Fatalf("List(%v) failed: %v", req, err)
+		}
+		if req.PageSize > 0 && len(repl.Vms) > int(req.PageSize) {
+			t.Errorf("List(%v) returned %d VMs", req, len(repl.Vms))
+		}
+		names = append(names, vmNames(repl.Vms)...)
+		if repl.NextPageToken == "" {
+			return names
+		}
+		req.PageToken = repl.NextPageToken
+	}
+}
+
+func TestListOrder(t *testing.T) {
+	s := newListServer(testVMs()...)
+
+	tests := []struct {
+		orderBy string
+		want    []string
+	}{
+		{orderBy: "", want: []string{"build", "cache", "db1", "db2", "web1", "web2", "web3"}},
+		{orderBy: "name desc", want: []string{"web3", "web2", "web1", "db2", "db1", "cache", "build"}},
+		{orderBy: "created", want: []string{"web1", "web3", "db2", "web2", "db1", "cache", "build"}},
+		// descending order reverses the ties on name too.
+		{orderBy: "mem DESC", want: []string{"cache", "db2", "db1", "web3", "build", "web2", "web1"}},
+		{orderBy: "ip", want: []string{"web1", "cache", "db2", "db1", "web2", "web3", "build"}},
+		{orderBy: "network", want: []string{"build", "cache", "web3", "db1", "db2", "web1", "web2"}},
+	}
+
+	for _, tt := range tests {
+		for _, pageSize := range []uint32{0, 1, 2, 3, 7, 8} {
+			got := listAll(t, s, &pb.ListVMRequest{OrderBy: tt.orderBy, PageSize: pageSize})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order %q, page size %d: got %v, want %v", tt.orderBy, pageSize, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestListPagesStableAcrossChanges(t *testing.T) {
+	s := newListServer(testVMs()...)
+
+	req := &pb.ListVMRequest{OrderBy: "created", PageSize: 3}
+	repl, err := s.List(context.Background(), req)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if got, want := vmNames(repl.Vms), []string{"web1", "web3", "db2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first page is %v, want %v", got, want)
+	}
+
+	// a VM before the cursor and the last VM of the page go away, a VM
+	// after it comes in; the next pages carry on from the same position.
+	s.inventory.put(&pb.VM{Name: "early", Created: 0})
+	s.inventory.remove("db2")
+	s.inventory.put(&pb.VM{Name: "late", Created: 10})
+
+	req.PageToken = repl.NextPageToken
+	got := listAll(t, s, req)
+	if want := []string{"web2", "db1", "cache", "build", "late"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next pages are %v, want %v", got, want)
+	}
+}
+
+func TestListPageSize(t *testing.T) {
+	vms := []*pb.VM{}
+	for i := 0; i < maxPageSize+5; i++ {
+		vms = append(vms, &pb.VM{Name: fmt.Sprintf("vm%04d", i)})
+	}
+	s := newListServer(vms...)
+
+	tests := []struct {
+		pageSize  uint32
+		wantLen   int
+		wantToken bool
+	}{
+		{pageSize: 0, wantLen: maxPageSize + 5},
+		{pageSize: 10, wantLen: 10, wantToken: true},
+		{pageSize: maxPageSize + 5, wantLen: maxPageSize, wantToken: true},
+		{pageSize: 1 << 31, wantLen: maxPageSize, wantToken: true},
+	}
+	for _, tt := range tests {
+		repl, err := s.List(context.Background(), &pb.ListVMRequest{PageSize: tt.pageSize})
+		if err != nil {
+			t.Fatalf("List with page size %d failed: %v", tt.pageSize, err)
+		}
+		if len(repl.Vms) != tt.wantLen || (repl.NextPageToken != "") != tt.wantToken {
+			t.Errorf("page size %d: got %d VMs and token %q, want %d VMs and token: %v", tt.pageSize, len(repl.Vms), repl.NextPageToken, tt.wantLen, tt.wantToken)
+		}
+	}
+
+	// an exactly full last page has no token.
+	repl, err := s.List(context.Background(), &pb.ListVMRequest{PageSize: 5, Filter: &pb.ListFilter{Name: "vm100"}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(repl.Vms) != 5 || repl.NextPageToken != "" {
+		t.Errorf("full last page: got %d VMs and token %q", len(repl.Vms), repl.NextPageToken)
+	}
+}
+
+func TestListInvalidPageToken(t *testing.T) {
+	s := newListServer(testVMs()...)
+
+	repl, err := s.List(context.Background(), &pb.ListVMRequest{PageSize: 2})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.ListVMRequest
+	}{
+		{name: "not base64", req: &pb.ListVMRequest{PageToken: "!!!"}},
+		{name: "not json", req: &pb.ListVMRequest{PageToken: base64.RawURLEncoding.EncodeToString([]byte("{"))}},
+		{name: "bad cursor", req: &pb.ListVMRequest{PageToken: base64.RawURLEncoding.EncodeToString([]byte(`{"q":"|name","c":"/w=="}`))}},
+		{name: "other order", req: &pb.ListVMRequest{PageSize: 2, OrderBy: "mem", PageToken: repl.NextPageToken}},
+		{name: "other filter", req: &pb.ListVMRequest{PageSize: 2, Filter: &pb.ListFilter{Network: "dev"}, PageToken: repl.NextPageToken}},
+	}
+	for _, tt := range tests {
+		_, err := s.List(context.Background(), tt.req)
+		if grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", tt.name, err)
+		}
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	s := newListServer(testVMs()...)
+
+	tests := []struct {
+		name    string
+		req     *pb.ListVMRequest
+		want    []string
+		wantErr bool
+	}{
+		{name: "name prefix", req: &pb.ListVMRequest{Filter: &pb.ListFilter{Name: "web"}}, want: []string{"web1", "web2", "web3"}},
+		{name: "name glob", req: &pb.ListVMRequest{Filter: &pb.ListFilter{Name: "*1"}}, want: []string{"db1", "web1"}},
+		{name: "bad glob", req: &pb.ListVMRequest{Filter: &pb.ListFilter{Name: "[a"}}, wantErr: true},
+		{name: "states", req: &pb.ListVMRequest{Filter: &pb.ListFilter{States: []pb.VM_State{pb.VM_RUNNING}}}, want: []string{"cache", "web3"}},
+		{name: "network", req: &pb.ListVMRequest{Filter: &pb.ListFilter{Network: "dev"}}, want: []string{"build", "cache", "web3"}},
+		{name: "labels", req: &pb.ListVMRequest{Filter: &pb.ListFilter{LabelSelector: "role=db,env!=prod"}}, want: []string{"db2"}},
+		{name: "deprecated labels", req: &pb.ListVMRequest{LabelSelector: "role=web"}, want: []string{"web1", "web2"}},
+		{name: "both labels", req: &pb.ListVMRequest{LabelSelector: "env", Filter: &pb.ListFilter{LabelSelector: "role=web"}}, want: []string{"web1"}},
+		{name: "bad labels", req: &pb.ListVMRequest{LabelSelector: "a=b,=c"}, wantErr: true},
+		{name: "bad order", req: &pb.ListVMRequest{OrderBy: "labels"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		repl, err := s.List(context.Background(), tt.req)
+		if tt.wantErr {
+			if grpc.Code(err) != codes.InvalidArgument {
+				t.Errorf("%s: got %v, want InvalidArgument", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: List failed: %v", tt.name, err)
+			continue
+		}
+		if got := vmNames(repl.Vms); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseOrderBy(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    vmOrder
+		wantErr bool
+	}{
+		{spec: "", want: vmOrder{field: "name"}},
+		{spec: "created", want: vmOrder{field: "created"}},
+		{spec: " ip  desc ", want: vmOrder{field: "ip", desc: true}},
+		{spec: "mem asc", wantErr: true},
+		{spec: "mem desc desc", wantErr: true},
+		{spec: "labels", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOrderBy(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOrderBy(%q) error = %v, want error: %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseOrderBy(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const maxVMNameLength = 63
+
+// VM names are published as dns labels and used as the default guest host
+// name, and volume names are derived from them with an underscore separator.
+// So new VMs must be named as host names: letters, digits and dashes, at most
+// 63 characters.
+//
+// VMs created before the policy keep working, but can't get data disks or
+// snapshots if their names don't comply, as the volume names could clash.
+var vmNameRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+func validateVMName(name string) error {
+	if len(name) > maxVMNameLength || !vmNameRe.MatchString(name) {
+		return fmt.Errorf("invalid vm name %q, must be a host name of at most %d characters", name, maxVMNameLength)
+	}
+	return nil
+}
@@ -19,26 +19,243 @@ limitations under the License.
 package server
 
 import (
-	"encoding/binary"
-	"math/rand"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net"
+	"path/filepath"
 )
 
-func generateIPv4(srcNet *net.IPNet) net.IP {
-	randIP := rand.Uint32()
-	ones, total := srcNet.Mask.Size()
-	bitmask := ((uint32(1) << uint(total-ones)) - 1)
-	netAddr := (uint32(srcNet.IP[0]) << 24) | (uint32(srcNet.IP[1]) << 16) | (uint32(srcNet.IP[2]) << 8) | uint32(srcNet.IP[3])
-	randIP = (randIP & bitmask) | netAddr
+// NetworkConfig is the configuration of a single VM network, as loaded from
+// the networks file.
+type NetworkConfig struct {
+	Name           string `json:"name"`
+	CIDR           string `json:"cidr"`
+	Gateway        string `json:"gateway"`
+	Bridge         string `json:"bridge"`
+	LibvirtNetwork string `json:"libvirt_network"`
+	DNSZone        string `json:"dns_zone"`
+	ReverseZone    string `json:"reverse_zone"`
+	Reserved       string `json:"reserved"`
 
-	if randIP&255 == 0 {
-		randIP++
-	} else if randIP&255 == 255 {
-		randIP--
+	IPv6CIDR        string `json:"ipv6_cidr"`
+	IPv6Gateway     string `json:"ipv6_gateway"`
+	IPv6ReverseZone string `json:"ipv6_reverse_zone"`
+	IPv6Reserved    string `json:"ipv6_reserved"`
+	// IPv6Mode is either "pool" (allocate sequentially from ipv6_cidr) or
+	// "eui64" (derive the address from the VM mac).
+	IPv6Mode string `json:"ipv6_mode"`
+}
+
+// Network is a subnet VMs get their addresses from.
+type Network struct {
+	Name           string
+	Subnet         *net.IPNet
+	Gateway        net.IP
+	Bridge         string
+	LibvirtNetwork string
+	DNSZone        string
+	ReverseZone    string
+
+	Subnet6      *net.IPNet
+	Gateway6     net.IP
+	EUI64        bool
+	ReverseZone6 string
+
+	reserved  []IPRange
+	reserved6 []IPRange
+	ipam      IPAllocator
+	ipam6     IPAllocator
+}
+
+// LoadNetworkConfigs reads a json list of network configs.
+func LoadNetworkConfigs(path string) ([]NetworkConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := []NetworkConfig{}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return configs, nil
+}
+
+// NewNetwork creates a network with its own ip allocator. Allocations are
+// persisted in stateDir, if set.
+func NewNetwork(cfg NetworkConfig, stateDir string) (*Network, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("network name not specified")
+	}
+
+	_, subnet, err := net.ParseCIDR(cfg.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cidr of network %s: %v", cfg.Name, err)
+	}
+
+	reserved, err := ParseIPRanges(cfg.Reserved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reserved addresses of network %s: %v", cfg.Name, err)
 	}
 
-	ip := make([]byte, 4)
-	binary.BigEndian.PutUint32(ip, randIP)
+	gateway, reserved, err := parseGateway(cfg.Name, cfg.Gateway, subnet, reserved)
+	if err != nil {
+		return nil, err
+	}
 
-	return ip
+	statePath := ""
+	if stateDir != "" {
+		statePath = filepath.Join(stateDir, cfg.Name+".json")
+	}
+
+	ipam, err := NewIPAllocator(subnet, reserved, statePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init ip allocator of network %s: %v", cfg.Name, err)
+	}
+
+	n := &Network{
+		Name:           cfg.Name,
+		Subnet:         subnet,
+		Gateway:        gateway,
+		Bridge:         cfg.Bridge,
+		LibvirtNetwork: cfg.LibvirtNetwork,
+		DNSZone:        cfg.DNSZone,
+		ReverseZone:    cfg.ReverseZone,
+		reserved:       reserved,
+		ipam:           ipam,
+	}
+
+	if cfg.IPv6CIDR == "" {
+		return n, nil
+	}
+
+	_, n.Subnet6, err = net.ParseCIDR(cfg.IPv6CIDR)
+	if err != nil || n.Subnet6.IP.To4() != nil {
+		return nil, fmt.Errorf("invalid ipv6 cidr %q of network %s", cfg.IPv6CIDR, cfg.Name)
+	}
+
+	reserved6, err := ParseIPRanges(cfg.IPv6Reserved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reserved ipv6 addresses of network %s: %v", cfg.Name, err)
+	}
+
+	n.ReverseZone6 = cfg.IPv6ReverseZone
+	n.Gateway6, reserved6, err = parseGateway(cfg.Name, cfg.IPv6Gateway, n.Subnet6, reserved6)
+	if err != nil {
+		return nil, err
+	}
+	n.reserved6 = reserved6
+
+	// eui64 addresses are reserved in the allocator too, to catch collisions.
+	statePath6 := ""
+	if stateDir != "" {
+		statePath6 = filepath.Join(stateDir, cfg.Name+".ipv6.json")
+	}
+	n.ipam6, err = NewIPAllocator(n.Subnet6, reserved6, statePath6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init ipv6 allocator of network %s: %v", cfg.Name, err)
+	}
+
+	switch cfg.IPv6Mode {
+	case "", "pool":
+	case "eui64":
+		if ones, _ := n.Subnet6.Mask.Size(); ones != 64 {
+			return nil, fmt.Errorf("eui64 mode of network %s needs a /64 prefix", cfg.Name)
+		}
+		n.EUI64 = true
+	default:
+		return nil, fmt.Errorf("unknown ipv6 mode %q of network %s", cfg.IPv6Mode, cfg.Name)
+	}
+
+	return n, nil
+}
+
+// parseGateway parses the optional gateway address and adds it to the
+// reserved ranges.
+func parseGateway(network string, spec string, subnet *net.IPNet, reserved []IPRange) (net.IP, []IPRange, error) {
+	if spec == "" {
+		return nil, reserved, nil
+	}
+
+	gateway := net.ParseIP(spec)
+	if gateway == nil || !subnet.Contains(gateway) {
+		return nil, nil, fmt.Errorf("invalid gateway %q of network %s", spec, network)
+	}
+	return gateway, append(reserved, IPRange{First: gateway, Last: gateway}), nil
+}
+
+// allocateIPv6 picks an ipv6 address for the VM. Returns nil if the network
+// has no ipv6.
+func (n *Network) allocateIPv6(owner string, mac net.HardwareAddr) (net.IP, error) {
+	if n.Subnet6 == nil {
+		return nil, nil
+	}
+	if n.EUI64 {
+		ip, err := eui64Address(n.Subnet6, mac)
+		if err != nil {
+			return nil, err
+		}
+		return ip, n.ipam6.Reserve(ip, owner)
+	}
+	return n.ipam6.Allocate(owner)
+}
+
+// reserveIPv6 marks the ipv6 address of an existing VM as used.
+func (n *Network) reserveIPv6(ip net.IP, owner string) error {
+	if n.ipam6 == nil {
+		return nil
+	}
+	return n.ipam6.Reserve(ip, owner)
+}
+
+// releaseIPv6 returns the ipv6 address to the pool.
+func (n *Network) releaseIPv6(ip net.IP) error {
+	if n.ipam6 == nil || ip == nil {
+		return nil
+	}
+	return n.ipam6.Release(ip)
+}
+
+// reverseZoneOf returns the reverse zone for the address, if configured.
+func (n *Network) reverseZoneOf(address string) string {
+	if addressRecordType(address) == "AAAA" {
+		return n.ReverseZone6
+	}
+	return n.ReverseZone
+}
+
+// maxMACAttempts bounds the retries of allocateMAC on collisions.
+const maxMACAttempts = 16
+
+// randomMAC generates a locally administered mac in the qemu range.
+func randomMAC() (net.HardwareAddr, error) {
+	mac := net.HardwareAddr{0x52, 0x54, 0x00, 0, 0, 0}
+	if _, err := rand.Read(mac[3:]); err != nil {
+		return nil, err
+	}
+	return mac, nil
+}
+
+// allocateMAC picks a random mac no other VM uses, along with the ipv6 address
+// of the VM, which in eui64 mode is derived from the mac and must be free as
+// well.
+func (s Server) allocateMAC(network *Network, owner string) (net.HardwareAddr, net.IP, error) {
+	for i := 0; i < maxMACAttempts; i++ {
+		mac, err := randomMAC()
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := s.inventory.findByMAC(mac.String()); ok {
+			continue
+		}
+
+		ip6, err := network.allocateIPv6(owner, mac)
+		if _, ok := err.(allocatedError); ok && network.EUI64 {
+			continue
+		}
+		return mac, ip6, err
+	}
+	return nil, nil, fmt.Errorf("no unused mac found in %d attempts", maxMACAttempts)
 }
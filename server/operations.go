@@ -0,0 +1,357 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+var (
+	operationRetention = flag.Duration("operation-retention", time.Hour, "how long finished create and destroy operations can be queried")
+)
+
+// operationTimeout limits the run time of a single operation.
+const operationTimeout = 30 * time.Minute
+
+// operation is a create or destroy running in the background.
+type operation struct {
+	mu sync.Mutex
+	op *pb.Operation
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// snapshot returns a copy of the current operation state.
+func (o *operation) snapshot() *pb.Operation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return proto.Clone(o.op).(*pb.Operation)
+}
+
+func (o *operation) finished() bool {
+	select {
+	case <-o.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// step marks the current step as done and starts the next one. Returns an
+// error if the operation was cancelled, so that it stops before the step.
+// The check is done with mu held, see CancelOperation.
+func (o *operation) step(ctx context.Context, name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch ctx.Err() {
+	case context.Canceled:
+		return grpc.Errorf(codes.Canceled, "operation cancelled before %s", name)
+	case context.DeadlineExceeded:
+		return grpc.Errorf(codes.DeadlineExceeded, "operation timed out before %s", name)
+	}
+
+	now := time.Now().Unix()
+	if n := len(o.op.Steps); n > 0 {
+		o.op.Steps[n-1].Status = pb.Operation_DONE
+		o.op.Steps[n-1].Finished = now
+	}
+	o.op.Steps = append(o.op.Steps, &pb.Operation_Step{
+		Name:    name,
+		Status:  pb.Operation_RUNNING,
+		Started: now,
+	})
+	return nil
+}
+
+// finish records the result of the operation.
+func (o *operation) finish(vm *pb.VM, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	status := pb.Operation_DONE
+	if err != nil {
+		status = pb.Operation_FAILED
+		if grpc.Code(err) == codes.Canceled {
+			status = pb.Operation_CANCELLED
+		}
+		o.op.ErrorCode = int32(grpc.Code(err))
+		o.op.Error = grpc.ErrorDesc(err)
+	}
+
+	now := time.Now().Unix()
+	if n := len(o.op.Steps); n > 0 {
+		o.op.Steps[n-1].Status = status
+		o.op.Steps[n-1].Finished = now
+	}
+	o.op.Status = status
+	o.op.Finished = now
+	o.op.Vm = vm
+
+	close(o.done)
+}
+
+// operationStore keeps track of the running and recently finished operations.
+type operationStore struct {
+	mu        sync.Mutex
+	ops       map[string]*operation
+	byRequest map[string]*operation
+}
+
+func newOperationStore() *operationStore {
+	return &operationStore{
+		ops:       map[string]*operation{},
+		byRequest: map[string]*operation{},
+	}
+}
+
+func newOperationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// retried returns the operation started earlier for the same client request
+// id, or nil if there is none.
+func (st *operationStore) retried(requestID string, kind pb.Operation_Kind, vmName string) (*operation, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.retriedLocked(requestID, kind, vmName)
+}
+
+// retriedLocked is retried for callers holding mu.
+func (st *operationStore) retriedLocked(requestID string, kind pb.Operation_Kind, vmName string) (*operation, error) {
+	if requestID == "" {
+		return nil, nil
+	}
+
+	o, ok := st.byRequest[requestID]
+	if !ok {
+		return nil, nil
+	}
+	// kind and vm name never change, no need to lock the operation.
+	if o.op.Kind != kind || o.op.VmName != vmName {
+		return nil, grpc.Errorf(codes.InvalidArgument, "request id %s was used for %s of %s", requestID, o.op.Kind, o.op.VmName)
+	}
+	return o, nil
+}
+
+// start runs fn in the background as a new operation. The operation context
+// is detached from ctx, as the client only waits for the operation to be
+// scheduled, but keeps its trace.
+//
+// If requestID is set and an operation with the same id is known, that one is
+// returned instead. Only one operation can run on a VM at a time.
+func (st *operationStore) start(ctx context.Context, requestID string, kind pb.Operation_Kind, vmName string, fn func(ctx context.Context, o *operation) (*pb.VM, error)) (*operation, error) {
+	id, err := newOperationID()
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to generate operation id: %v", err)
+	}
+
+	st.mu.Lock()
+	st.prune()
+
+	retried, err := st.retriedLocked(requestID, kind, vmName)
+	if err != nil || retried != nil {
+		st.mu.Unlock()
+		return retried, err
+	}
+	for _, other := range st.ops {
+		if !other.finished() && other.op.VmName == vmName {
+			st.mu.Unlock()
+			return nil, grpc.Errorf(codes.Aborted, "operation %s on %s is in progress", other.op.Id, vmName)
+		}
+	}
+
+	opCtx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	o := &operation{
+		op: &pb.Operation{
+			Id:        id,
+			Kind:      kind,
+			VmName:    vmName,
+			Status:    pb.Operation_RUNNING,
+			Created:   time.Now().Unix(),
+			RequestId: requestID,
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	st.ops[id] = o
+	if requestID != "" {
+		st.byRequest[requestID] = o
+	}
+	st.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		sp, opCtx := opentracing.StartSpanFromContext(
+			opentracing.ContextWithSpan(opCtx, opentracing.SpanFromContext(ctx)),
+			"operation."+kind.String())
+		sp.SetTag("operation", id)
+		sp.SetTag("vm", vmName)
+		defer sp.Finish()
+
+		vm, err := fn(opCtx, o)
+		if err != nil && opCtx.Err() == context.Canceled && grpc.Code(err) != codes.Canceled {
+			err = grpc.Errorf(codes.Canceled, "operation cancelled: %v", grpc.ErrorDesc(err))
+		}
+		if err != nil {
+			sp.SetTag("error", true)
+			glog.Errorf("%s of %s failed: %v", kind, vmName, err)
+		}
+		o.finish(vm, err)
+	}()
+
+	return o, nil
+}
+
+// prune forgets operations finished more than operationRetention ago. Must be
+// called with mu held.
+func (st *operationStore) prune() {
+	cutoff := time.Now().Add(-*operationRetention).Unix()
+	for id, o := range st.ops {
+		if !o.finished() {
+			continue
+		}
+		o.mu.Lock()
+		expired := o.op.Finished < cutoff
+		o.mu.Unlock()
+		if expired {
+			delete(st.ops, id)
+			if o.op.RequestId != "" {
+				delete(st.byRequest, o.op.RequestId)
+			}
+		}
+	}
+}
+
+func (st *operationStore) get(id string) (*operation, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	o, ok := st.ops[id]
+	if !ok {
+		return nil, grpc.Errorf(codes.NotFound, "operation %s not found", id)
+	}
+	return o, nil
+}
+
+// list returns the operations on the VM, or all of them, oldest first.
+func (st *operationStore) list(vmName string) []*pb.Operation {
+	st.mu.Lock()
+	st.prune()
+	ops := make([]*operation, 0, len(st.ops))
+	for _, o := range st.ops {
+		ops = append(ops, o)
+	}
+	st.mu.Unlock()
+
+	list := []*pb.Operation{}
+	for _, o := range ops {
+		op := o.snapshot()
+		if vmName != "" && op.VmName != vmName {
+			continue
+		}
+		list = append(list, op)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Created != list[j].Created {
+			return list[i].Created < list[j].Created
+		}
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+// GetOperation is GRPC handler for GetOperation API.
+func (s Server) GetOperation(ctx context.Context, in *pb.GetOperationRequest) (*pb.Operation, error) {
+	o, err := s.operations.get(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return o.snapshot(), nil
+}
+
+// ListOperations is GRPC handler for ListOperations API.
+func (s Server) ListOperations(ctx context.Context, in *pb.ListOperationsRequest) (*pb.ListOperationsReply, error) {
+	return &pb.ListOperationsReply{Operations: s.operations.list(in.GetVmName())}, nil
+}
+
+// WaitOperation is GRPC handler for WaitOperation API. It returns once the
+// operation is over, or with its current state on timeout.
+func (s Server) WaitOperation(ctx context.Context, in *pb.WaitOperationRequest) (*pb.Operation, error) {
+	o, err := s.operations.get(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout <-chan time.Time
+	if in.GetTimeout() > 0 {
+		timeout = time.After(time.Duration(in.GetTimeout()) * time.Second)
+	}
+
+	select {
+	case <-o.done:
+	case <-timeout:
+	case <-ctx.Done():
+		return nil, grpc.Errorf(libvirtErrorCode(ctx.Err()), "operation %s is still running: %v", in.GetId(), ctx.Err())
+	}
+	return o.snapshot(), nil
+}
+
+// CancelOperation is GRPC handler for CancelOperation API. The operation stops
+// before its next step. A create rolls back what was done so far; a destroy
+// can't be undone, so it can only be cancelled before its first step.
+// Cancelling a finished operation has no effect.
+func (s Server) CancelOperation(ctx context.Context, in *pb.CancelOperationRequest) (*pb.Operation, error) {
+	o, err := s.operations.get(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	if o.op.Kind == pb.Operation_DESTROY && len(o.op.Steps) > 0 && !o.finished() {
+		o.mu.Unlock()
+		return nil, grpc.Errorf(codes.FailedPrecondition, "destroy %s is past its first step and can't be cancelled", in.GetId())
+	}
+	o.cancel()
+	o.mu.Unlock()
+
+	return o.snapshot(), nil
+}
@@ -0,0 +1,71 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"github.com/google/vmregistry/powerdns"
+)
+
+// PowerDNSProvider manages records through the PowerDNS http api.
+type PowerDNSProvider struct {
+	baseURL string
+	apikey  string
+}
+
+// NewPowerDNSProvider creates a provider for the PowerDNS server at baseURL.
+func NewPowerDNSProvider(baseURL string, apikey string) *PowerDNSProvider {
+	return &PowerDNSProvider{baseURL: baseURL, apikey: apikey}
+}
+
+func (p PowerDNSProvider) zone(zone string) *powerdns.PowerDNS {
+	return powerdns.New(p.baseURL, "localhost", canonicalZone(zone), p.apikey)
+}
+
+func (p PowerDNSProvider) AddRecord(zone string, rec DNSRecord) error {
+	_, err := p.zone(zone).AddRecord(rec.Name, rec.Type, rec.TTL, []string{rec.Content})
+	return err
+}
+
+func (p PowerDNSProvider) RemoveRecord(zone string, rec DNSRecord) error {
+	_, err := p.zone(zone).DeleteRecord(rec.Name, rec.Type, rec.TTL, []string{rec.Content})
+	return err
+}
+
+func (p PowerDNSProvider) ListRecords(zone string) ([]DNSRecord, error) {
+	z, err := p.zone(zone).GetZone()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []DNSRecord{}
+	for _, set := range z.RRsets {
+		for _, r := range set.Records {
+			if r.Disabled {
+				continue
+			}
+			records = append(records, DNSRecord{
+				Name:    set.Name,
+				Type:    set.Type,
+				TTL:     set.TTL,
+				Content: r.Content,
+			})
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,171 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"flag"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/google/vmregistry/api"
+
+	"github.com/golang/glog"
+	libvirt "github.com/libvirt/libvirt-go"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+var (
+	shutdownTimeout = flag.Duration("shutdown-timeout", time.Minute, "time to wait for a graceful vm shutdown before stopping it forcefully")
+)
+
+const shutdownPollInterval = 500 * time.Millisecond
+
+// stopGracePeriod is the time left for the forced stop after the shutdown
+// timeout expires.
+const stopGracePeriod = 30 * time.Second
+
+// powerAction looks up the domain by name, applies the action and returns the
+// updated VM details.
+func (s Server) powerAction(ctx context.Context, name string, op string, action func(d *libvirt.Domain) error) (*pb.VM, error) {
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = traceDomainAction(ctx, op, func() error { return action(d) })
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "%s failed on vm %s: %v", op, name, err)
+	}
+
+	vm, err := describeDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	s.inventory.put(vm)
+	return vm, nil
+}
+
+// waitForShutoff polls the domain state until it's shut off or timeout expires.
+func waitForShutoff(ctx context.Context, d *libvirt.Domain, timeout time.Duration) (bool, error) {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := traceDomainGetState(ctx, *d)
+		if err != nil {
+			return false, err
+		}
+		if state == libvirt.DOMAIN_SHUTOFF {
+			return true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-deadline:
+			return false, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start is GRPC handler for Start API.
+func (s Server) Start(ctx context.Context, in *pb.StartRequest) (*pb.VM, error) {
+	return s.powerAction(ctx, in.GetName(), "Create", func(d *libvirt.Domain) error {
+		return d.Create()
+	})
+}
+
+// Stop is GRPC handler for Stop API.
+//
+// The guest is asked to shut down gracefully first, and is stopped forcefully
+// if it doesn't comply within the timeout. The wait and the fallback run in a
+// context detached from the request, so a client giving up earlier doesn't
+// leave a hung guest running.
+func (s Server) Stop(ctx context.Context, in *pb.StopRequest) (*pb.VM, error) {
+	if in.GetForce() {
+		return s.powerAction(ctx, in.GetName(), "Destroy", func(d *libvirt.Domain) error {
+			return d.Destroy()
+		})
+	}
+
+	timeout := *shutdownTimeout
+	if in.GetTimeout() != 0 {
+		timeout = time.Duration(in.GetTimeout()) * time.Second
+	}
+
+	return s.powerAction(ctx, in.GetName(), "Shutdown", func(d *libvirt.Domain) error {
+		err := d.Shutdown()
+		if err != nil {
+			return err
+		}
+
+		stopCtx, cancel := context.WithTimeout(
+			opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx)),
+			timeout+stopGracePeriod)
+		defer cancel()
+
+		off, err := waitForShutoff(stopCtx, d, timeout)
+		if err != nil {
+			return err
+		}
+		if off {
+			return nil
+		}
+
+		glog.Infof("vm %s didn't shut down in %v, stopping it", in.GetName(), timeout)
+		return traceDomainAction(stopCtx, "Destroy", d.Destroy)
+	})
+}
+
+// Reboot is GRPC handler for Reboot API.
+func (s Server) Reboot(ctx context.Context, in *pb.RebootRequest) (*pb.VM, error) {
+	return s.powerAction(ctx, in.GetName(), "Reboot", func(d *libvirt.Domain) error {
+		return d.Reboot(libvirt.DOMAIN_REBOOT_DEFAULT)
+	})
+}
+
+// Reset is GRPC handler for Reset API.
+func (s Server) Reset(ctx context.Context, in *pb.ResetRequest) (*pb.VM, error) {
+	return s.powerAction(ctx, in.GetName(), "Reset", func(d *libvirt.Domain) error {
+		return d.Reset(0)
+	})
+}
+
+// Suspend is GRPC handler for Suspend API.
+func (s Server) Suspend(ctx context.Context, in *pb.SuspendRequest) (*pb.VM, error) {
+	return s.powerAction(ctx, in.GetName(), "Suspend", func(d *libvirt.Domain) error {
+		return d.Suspend()
+	})
+}
+
+// Resume is GRPC handler for Resume API.
+func (s Server) Resume(ctx context.Context, in *pb.ResumeRequest) (*pb.VM, error) {
+	return s.powerAction(ctx, in.GetName(), "Resume", func(d *libvirt.Domain) error {
+		return d.Resume()
+	})
+}
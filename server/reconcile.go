@@ -0,0 +1,267 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/golang/glog"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dnsDrift = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vmregistry_dns_drift_rrsets",
+		Help: "Record sets out of sync with the VMs as of the last dns reconciliation.",
+	}, []string{"zone", "kind"})
+
+	dnsRepairs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vmregistry_dns_repairs_total",
+		Help: "Record sets fixed by the dns reconciler.",
+	}, []string{"zone", "kind"})
+
+	dnsReconcileRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vmregistry_dns_reconcile_runs_total",
+		Help: "Runs of the dns reconciler, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(dnsDrift, dnsRepairs, dnsReconcileRuns)
+}
+
+// Kinds of dns drift.
+const (
+	driftMissing  = "missing"
+	driftMismatch = "mismatch"
+	driftStale    = "stale"
+)
+
+type rrsetKey struct {
+	name   string
+	rrtype string
+}
+
+// normalizeContent makes record contents comparable across providers.
+func normalizeContent(rrtype string, content string) string {
+	switch rrtype {
+	case "A", "AAAA":
+		if ip := net.ParseIP(content); ip != nil {
+			return ip.String()
+		}
+	case "PTR":
+		return strings.ToLower(canonicalZone(content))
+	}
+	return content
+}
+
+// dnsZones returns all the zones records of VMs are published in.
+func (s Server) dnsZones() []string {
+	seen := map[string]bool{}
+	zones := []string{}
+	for _, n := range s.networks {
+		for _, z := range []string{n.DNSZone, n.ReverseZone, n.ReverseZone6} {
+			if z == "" || seen[canonicalZone(z)] {
+				continue
+			}
+			seen[canonicalZone(z)] = true
+			zones = append(zones, z)
+		}
+	}
+	return zones
+}
+
+// desiredDNSRecords returns the records of all existing VMs, by zone. Domains
+// that can't be described are skipped, and then the returned flag is false:
+// the records of those VMs aren't known, so none can be told to be stale.
+func (s Server) desiredDNSRecords(ctx context.Context) (map[string]map[rrsetKey]DNSRecord, bool, error) {
+	listing, err := describeAllDomains(ctx, s.conn)
+	if err != nil {
+		return nil, false, err
+	}
+
+	desired := map[string]map[rrsetKey]DNSRecord{}
+	for _, vm := range listing.vms {
+		network := s.networkOf(vm)
+		if network == nil || network.DNSZone == "" {
+			continue
+		}
+
+		records, err := vmRecords(network, vm.Name, vm.Ip, vm.Ipv6)
+		if err != nil {
+			glog.Warningf("failed to build dns records of %s: %v", vm.Name, err)
+			continue
+		}
+		for _, rec := range records {
+			zone := strings.ToLower(canonicalZone(rec.Zone))
+			if desired[zone] == nil {
+				desired[zone] = map[rrsetKey]DNSRecord{}
+			}
+			key := rrsetKey{name: strings.ToLower(rec.Name), rrtype: rec.Type}
+			desired[zone][key] = rec.DNSRecord
+		}
+	}
+	return desired, listing.complete && len(listing.skipped) == 0, nil
+}
+
+// reconcileZone compares the records of a single zone with the desired ones
+// and fixes the differences unless dryRun is set. Returns the number of
+// record sets out of sync.
+//
+// Only the record sets of existing VMs, and the ones the provider knows
+// vmregistry published, are considered; anything else in the zone is left
+// alone. Without such a provider, or without removeStale, stale records are
+// never removed.
+func (s Server) reconcileZone(zone string, desired map[rrsetKey]DNSRecord, removeStale bool, dryRun bool) (int, error) {
+	records, err := s.dns.ListRecords(zone)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list records of %s: %v", zone, err)
+	}
+	owner, _ := s.dns.(dnsRecordOwner)
+	if !removeStale {
+		owner = nil
+	}
+
+	actual := map[rrsetKey]map[string]DNSRecord{}
+	for _, rec := range records {
+		key := rrsetKey{name: strings.ToLower(rec.Name), rrtype: rec.Type}
+		if _, ok := desired[key]; !ok && (owner == nil || !owner.owns(zone, rec)) {
+			continue
+		}
+		if actual[key] == nil {
+			actual[key] = map[string]DNSRecord{}
+		}
+		actual[key][normalizeContent(rec.Type, rec.Content)] = rec
+	}
+
+	drift := map[string][]DNSRecord{}
+	for key, rec := range desired {
+		contents, ok := actual[key]
+		if !ok {
+			drift[driftMissing] = append(drift[driftMissing], rec)
+			continue
+		}
+		if _, ok := contents[normalizeContent(rec.Type, rec.Content)]; !ok || len(contents) != 1 {
+			drift[driftMismatch] = append(drift[driftMismatch], rec)
+		}
+	}
+	for key, contents := range actual {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		// removal works on the whole set, any of its records will do.
+		for _, rec := range contents {
+			drift[driftStale] = append(drift[driftStale], rec)
+			break
+		}
+	}
+
+	total := 0
+	for _, kind := range []string{driftMissing, driftMismatch, driftStale} {
+		dnsDrift.WithLabelValues(zone, kind).Set(float64(len(drift[kind])))
+		total += len(drift[kind])
+
+		for _, rec := range drift[kind] {
+			glog.Warningf("dns drift in %s: %s %s %s %s", zone, kind, rec.Name, rec.Type, rec.Content)
+			if dryRun {
+				continue
+			}
+
+			if kind == driftStale {
+				err = s.dns.RemoveRecord(zone, rec)
+			} else {
+				err = s.dns.AddRecord(zone, rec)
+			}
+			if err != nil {
+				glog.Errorf("failed to fix %s %s in %s: %v", rec.Name, rec.Type, zone, err)
+				continue
+			}
+			dnsRepairs.WithLabelValues(zone, kind).Inc()
+		}
+	}
+
+	return total, nil
+}
+
+// ReconcileDNS makes the records in the dns zones match the existing domains:
+// records of VMs missing from the zones or pointing elsewhere are published,
+// and records vmregistry published for VMs that no longer exist are removed.
+// With dryRun, the drift is only reported.
+func (s Server) ReconcileDNS(ctx context.Context, dryRun bool) error {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "dns.Reconcile")
+	sp.SetTag("dry_run", dryRun)
+	defer sp.Finish()
+
+	desired, complete, err := s.desiredDNSRecords(ctx)
+	if err != nil {
+		sp.SetTag("error", true)
+		dnsReconcileRuns.WithLabelValues("error").Inc()
+		return err
+	}
+
+	if !complete {
+		glog.Warningf("not removing stale dns records, some domains couldn't be described")
+	}
+
+	var lastErr error
+	for _, zone := range s.dnsZones() {
+		drift, err := s.reconcileZone(zone, desired[strings.ToLower(canonicalZone(zone))], complete, dryRun)
+		if err != nil {
+			glog.Errorf("dns reconciliation of %s failed: %v", zone, err)
+			lastErr = err
+			continue
+		}
+		if drift > 0 {
+			glog.Infof("found %d record sets out of sync in %s", drift, zone)
+		}
+	}
+
+	if lastErr != nil {
+		sp.SetTag("error", true)
+		dnsReconcileRuns.WithLabelValues("error").Inc()
+		return lastErr
+	}
+	dnsReconcileRuns.WithLabelValues("success").Inc()
+	return nil
+}
+
+// RunDNSReconciler calls ReconcileDNS every interval until ctx is done.
+func (s Server) RunDNSReconciler(ctx context.Context, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.ReconcileDNS(ctx, dryRun)
+		if err != nil {
+			glog.Errorf("dns reconciliation failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/glog"
+	libvirt "github.com/libvirt/libvirt-go"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// ResizeDisk is GRPC handler for ResizeDisk API. Block volumes are resized
+// first, then a running domain is told about the new size. qemu holds a lock
+// on the qcow2 images of running domains, so those are grown by the block
+// resize alone.
+func (s Server) ResizeDisk(ctx context.Context, in *pb.ResizeDiskRequest) (*pb.VM, error) {
+	name := in.GetName()
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+	defer s.locks.lock(name)()
+
+	size := in.GetSize()
+	if size == 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "size not specified")
+	}
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	dom, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	md := dom.Metadata.VMRegistry
+
+	active, err := d.IsActive()
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+
+	switch {
+	case size == md.Size:
+		return describeDomain(ctx, *d)
+	case md.Size == 0 && !in.GetForce():
+		return nil, grpc.Errorf(codes.FailedPrecondition, "disk size of %s is unknown, resizing needs force", name)
+	case size < md.Size && !in.GetForce():
+		return nil, grpc.Errorf(codes.FailedPrecondition, "refusing to shrink disk of %s from %d to %d bytes without force", name, md.Size, size)
+	case size < md.Size && active:
+		return nil, grpc.Errorf(codes.FailedPrecondition, "%s must be shut off to shrink its disk", name)
+	}
+
+	if !active || s.storage.StorageFormat() == "raw" {
+		err = s.storage.ResizeStorage(ctx, name, size)
+		if grpc.Code(err) == codes.Unimplemented {
+			return nil, err
+		}
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "failed to resize storage: %v", err)
+		}
+	}
+
+	if active {
+		err = traceDomainBlockResize(ctx, *d, s.storage.StorageBlockDevice(name), size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	md.Size = size
+	err = traceDomainSetMetadata(ctx, *d, md)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, err := describeDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	s.inventory.put(vm)
+	return vm, nil
+}
+
+// resizeConfig applies the sizing to the persistent domain definition, raising
+// the maximums when needed.
+func resizeConfig(ctx context.Context, d libvirt.Domain, config libvirtDomain, mem uint64, cores uint32) error {
+	if cores != 0 {
+		if cores > config.VCPU.Max {
+			err := traceDomainAction(ctx, "SetVcpusFlags", func() error {
+				return d.SetVcpusFlags(uint(cores), libvirt.DOMAIN_VCPU_CONFIG|libvirt.DOMAIN_VCPU_MAXIMUM)
+			})
+			if err != nil {
+				return grpc.Errorf(libvirtErrorCode(err), "failed to set maximum vcpus: %v", err)
+			}
+		}
+		err := traceDomainAction(ctx, "SetVcpusFlags", func() error {
+			return d.SetVcpusFlags(uint(cores), libvirt.DOMAIN_VCPU_CONFIG)
+		})
+		if err != nil {
+			return grpc.Errorf(libvirtErrorCode(err), "failed to set vcpus: %v", err)
+		}
+	}
+
+	if mem != 0 {
+		kib := mem << 20
+		if mem<<30 > config.Memory.bytes() {
+			err := traceDomainAction(ctx, "SetMemoryFlags", func() error {
+				return d.SetMemoryFlags(kib, libvirt.DOMAIN_MEM_CONFIG|libvirt.DOMAIN_MEM_MAXIMUM)
+			})
+			if err != nil {
+				return grpc.Errorf(libvirtErrorCode(err), "failed to set maximum memory: %v", err)
+			}
+		}
+		err := traceDomainAction(ctx, "SetMemoryFlags", func() error {
+			return d.SetMemoryFlags(kib, libvirt.DOMAIN_MEM_CONFIG)
+		})
+		if err != nil {
+			return grpc.Errorf(libvirtErrorCode(err), "failed to set memory: %v", err)
+		}
+	}
+	return nil
+}
+
+// resizeLive applies as much of the sizing to the running domain as its
+// limits allow: vcpus up to the maximum vcpus, memory up to the maximum
+// memory through the balloon. Returns true if the rest needs a reboot.
+func resizeLive(ctx context.Context, d libvirt.Domain, name string, mem uint64, cores uint32) (bool, error) {
+	live, err := parseLiveDomain(ctx, d)
+	if err != nil {
+		return false, err
+	}
+
+	rebootRequired := false
+	if cores != 0 && cores != live.VCPU.count() {
+		if cores > live.VCPU.Max {
+			rebootRequired = true
+		} else {
+			err := traceDomainAction(ctx, "SetVcpusFlags", func() error {
+				return d.SetVcpusFlags(uint(cores), libvirt.DOMAIN_VCPU_LIVE)
+			})
+			if err != nil {
+				glog.Warningf("failed to set live vcpus of %s, needs a reboot: %v", name, err)
+				rebootRequired = true
+			}
+		}
+	}
+
+	if mem != 0 && mem<<30 != live.memoryBytes() {
+		if mem<<30 > live.Memory.bytes() {
+			rebootRequired = true
+		} else {
+			err := traceDomainAction(ctx, "SetMemoryFlags", func() error {
+				return d.SetMemoryFlags(mem<<20, libvirt.DOMAIN_MEM_LIVE)
+			})
+			if err != nil {
+				glog.Warningf("failed to set live memory of %s, needs a reboot: %v", name, err)
+				rebootRequired = true
+			}
+		}
+	}
+	return rebootRequired, nil
+}
+
+// Resize is GRPC handler for Resize API. The persistent definition always gets
+// the new sizing, a running domain as much of it as can be hotplugged.
+func (s Server) Resize(ctx context.Context, in *pb.ResizeRequest) (*pb.ResizeReply, error) {
+	name := in.GetName()
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+	defer s.locks.lock(name)()
+
+	if in.GetMem() == 0 && in.GetCores() == 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "neither mem nor cores specified")
+	}
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := d.IsActive()
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+
+	err = resizeConfig(ctx, *d, config, in.GetMem(), in.GetCores())
+	if err != nil {
+		return nil, err
+	}
+
+	rebootRequired := false
+	if active {
+		rebootRequired, err = resizeLive(ctx, *d, name, in.GetMem(), in.GetCores())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	vm, err := describeDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	s.inventory.put(vm)
+	return &pb.ResizeReply{Vm: vm, RebootRequired: rebootRequired}, nil
+}
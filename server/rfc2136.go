@@ -0,0 +1,167 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// tsigFudge is the allowed clock skew of signed messages, in seconds.
+const tsigFudge = 300
+
+// RFC2136Provider manages records with dns dynamic updates (RFC 2136), e.g.
+// on BIND. Records are listed with zone transfers. Messages are signed with
+// TSIG if a key is configured.
+type RFC2136Provider struct {
+	server        string
+	tsigKey       string
+	tsigSecret    string
+	tsigAlgorithm string
+}
+
+// NewRFC2136Provider creates a provider that sends updates to the server
+// (host:port). The TSIG key name and base64 secret can be empty to send
+// unsigned messages; algorithm defaults to hmac-sha256.
+func NewRFC2136Provider(server string, tsigKey string, tsigSecret string, tsigAlgorithm string) (*RFC2136Provider, error) {
+	if server == "" {
+		return nil, fmt.Errorf("rfc2136 server address is not set")
+	}
+	if (tsigKey == "") != (tsigSecret == "") {
+		return nil, fmt.Errorf("tsig needs both a key name and a secret")
+	}
+	if tsigAlgorithm == "" {
+		tsigAlgorithm = dns.HmacSHA256
+	}
+
+	algorithm := dns.Fqdn(strings.ToLower(tsigAlgorithm))
+	switch algorithm {
+	case dns.HmacMD5, dns.HmacSHA1, dns.HmacSHA256, dns.HmacSHA512:
+	default:
+		return nil, fmt.Errorf("unsupported tsig algorithm %q", tsigAlgorithm)
+	}
+
+	p := &RFC2136Provider{
+		server:        server,
+		tsigSecret:    tsigSecret,
+		tsigAlgorithm: algorithm,
+	}
+	if tsigKey != "" {
+		p.tsigKey = dns.Fqdn(tsigKey)
+	}
+	return p, nil
+}
+
+func (p RFC2136Provider) tsigSecrets() map[string]string {
+	if p.tsigKey == "" {
+		return nil
+	}
+	return map[string]string{p.tsigKey: p.tsigSecret}
+}
+
+func (p RFC2136Provider) sign(m *dns.Msg) {
+	if p.tsigKey != "" {
+		m.SetTsig(p.tsigKey, p.tsigAlgorithm, tsigFudge, time.Now().Unix())
+	}
+}
+
+func (p RFC2136Provider) update(m *dns.Msg) error {
+	p.sign(m)
+
+	c := &dns.Client{
+		Net:        "tcp",
+		TsigSecret: p.tsigSecrets(),
+	}
+	r, _, err := c.Exchange(m, p.server)
+	if err != nil {
+		return fmt.Errorf("dns update failed: %v", err)
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("dns update failed: %s", dns.RcodeToString[r.Rcode])
+	}
+	return nil
+}
+
+func (rec DNSRecord) rr() (dns.RR, error) {
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", rec.Name, rec.TTL, rec.Type, rec.Content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid record %s %s: %v", rec.Name, rec.Type, err)
+	}
+	return rr, nil
+}
+
+func (p RFC2136Provider) AddRecord(zone string, rec DNSRecord) error {
+	rr, err := rec.rr()
+	if err != nil {
+		return err
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(canonicalZone(zone))
+	m.RemoveRRset([]dns.RR{rr})
+	m.Insert([]dns.RR{rr})
+	return p.update(m)
+}
+
+func (p RFC2136Provider) RemoveRecord(zone string, rec DNSRecord) error {
+	rr, err := rec.rr()
+	if err != nil {
+		return err
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(canonicalZone(zone))
+	m.RemoveRRset([]dns.RR{rr})
+	return p.update(m)
+}
+
+func (p RFC2136Provider) ListRecords(zone string) ([]DNSRecord, error) {
+	m := new(dns.Msg)
+	m.SetAxfr(canonicalZone(zone))
+	p.sign(m)
+
+	t := &dns.Transfer{TsigSecret: p.tsigSecrets()}
+	envelopes, err := t.In(m, p.server)
+	if err != nil {
+		return nil, fmt.Errorf("zone transfer of %s failed: %v", zone, err)
+	}
+
+	records := []DNSRecord{}
+	for e := range envelopes {
+		if e.Error != nil {
+			return nil, fmt.Errorf("zone transfer of %s failed: %v", zone, e.Error)
+		}
+		for _, rr := range e.RR {
+			h := rr.Header()
+			if h.Rrtype == dns.TypeSOA {
+				continue
+			}
+			records = append(records, DNSRecord{
+				Name:    h.Name,
+				Type:    dns.TypeToString[h.Rrtype],
+				TTL:     int(h.Ttl),
+				Content: strings.TrimPrefix(rr.String(), h.String()),
+			})
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,99 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/golang/glog"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// rollbackTimeout limits the time spent undoing a failed operation.
+const rollbackTimeout = 2 * time.Minute
+
+type sagaStep struct {
+	name       string
+	compensate func(ctx context.Context) error
+}
+
+// saga tracks the completed steps of a multi-step operation together with
+// the actions that undo them. Unless the saga is marked as done, the
+// compensating actions run in reverse order.
+type saga struct {
+	name  string
+	steps []sagaStep
+	done  bool
+}
+
+func newSaga(name string) *saga {
+	return &saga{name: name}
+}
+
+// onRollback registers the compensating action for a completed step.
+func (s *saga) onRollback(step string, compensate func(ctx context.Context) error) {
+	s.steps = append(s.steps, sagaStep{name: step, compensate: compensate})
+}
+
+// commit marks the saga as successfully completed.
+func (s *saga) commit() {
+	s.done = true
+}
+
+// rollbackUnlessCommitted undoes all the registered steps if the saga wasn't
+// committed. It is meant to be deferred right after the saga is created.
+//
+// Compensations run in a context detached from the cancellation of ctx, as the
+// most common reason to roll back is the client giving up on the request.
+func (s *saga) rollbackUnlessCommitted(ctx context.Context) {
+	if s.done || len(s.steps) == 0 {
+		return
+	}
+
+	rollbackCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	sp, rollbackCtx := opentracing.StartSpanFromContext(
+		opentracing.ContextWithSpan(rollbackCtx, opentracing.SpanFromContext(ctx)),
+		"saga.Rollback")
+	sp.SetTag("saga", s.name)
+	defer sp.Finish()
+
+	glog.Warningf("%s failed, rolling back %d steps", s.name, len(s.steps))
+
+	for i := len(s.steps) - 1; i >= 0; i-- {
+		step := s.steps[i]
+
+		stepSp, stepCtx := opentracing.StartSpanFromContext(rollbackCtx, "saga.Compensate")
+		stepSp.SetTag("step", step.name)
+
+		err := step.compensate(stepCtx)
+		if err != nil {
+			stepSp.SetTag("error", true)
+			stepSp.LogFields(log.Error(err))
+			glog.Errorf("%s: failed to roll back %s: %v", s.name, step.name, err)
+		} else {
+			glog.Infof("%s: rolled back %s", s.name, step.name)
+		}
+		stepSp.Finish()
+	}
+}
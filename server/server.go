@@ -19,10 +19,9 @@ limitations under the License.
 package server
 
 import (
-	"bytes"
 	"encoding/xml"
-	"html/template"
 	"net"
+	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -48,143 +47,272 @@ func extractIP(dom libvirtDomain) string {
 }
 
 type StorageManager interface {
+	// CreateStorage creates a volume from the source image, or a blank one if
+	// sourceImage is empty.
 	CreateStorage(ctx context.Context, name string, size uint64, sourceImage string) error
 	RemoveStorage(ctx context.Context, name string) error
+	// ResizeStorage changes the volume size. Callers make sure shrinking is
+	// intended.
+	ResizeStorage(ctx context.Context, name string, size uint64) error
+	// CreateSnapshot takes a point in time copy of the volume.
+	CreateSnapshot(ctx context.Context, name string, snapshot string) error
+	// RevertSnapshot brings the volume back to the snapshot, which is kept.
+	// Callers make sure the volume isn't in use.
+	RevertSnapshot(ctx context.Context, name string, snapshot string) error
+	RemoveSnapshot(ctx context.Context, name string, snapshot string) error
 	StorageBlockDevice(name string) string
+	// StorageFormat is the libvirt driver type of the volumes, raw block
+	// devices or qcow2 files.
+	StorageFormat() string
 }
 
 // Server is GRPC server.
 type Server struct {
-	conn    *libvirt.Connect
-	storage StorageManager
-	vmNet   *net.IPNet
-	dnsCli  *DnsClient
+	conn           *libvirt.Connect
+	storage        StorageManager
+	seeds          SeedManager
+	networks       map[string]*Network
+	defaultNetwork *Network
+	dns            DNSProvider
+	events         *eventHub
+	inventory      *inventory
+	operations     *operationStore
+	locks          *vmLocks
+	flavors        map[string]*Flavor
+
+	templates       map[string]*DomainTemplate
+	defaultTemplate *DomainTemplate
+}
 
-	xmlTemplate *template.Template
+// NewServer creates a new server instance. The first of the networks and of
+// the templates are used for VMs that don't ask for a specific one. VMs can't
+// get cloud-init seeds if seeds is nil.
+func NewServer(conn *libvirt.Connect, storage StorageManager, seeds SeedManager, networks []*Network, flavors []*Flavor, templates []*DomainTemplate, dns DNSProvider) Server {
+	s := Server{
+		conn:           conn,
+		storage:        storage,
+		seeds:          seeds,
+		networks:       map[string]*Network{},
+		defaultNetwork: networks[0],
+		dns:            dns,
+		events:         newEventHub(),
+		inventory:      newInventory(),
+		operations:     newOperationStore(),
+		locks:          newVMLocks(),
+		flavors:        map[string]*Flavor{},
+
+		templates:       map[string]*DomainTemplate{},
+		defaultTemplate: templates[0],
+	}
+	for _, n := range networks {
+		s.networks[n.Name] = n
+	}
+	for _, f := range flavors {
+		s.flavors[f.Name] = f
+	}
+	for _, t := range templates {
+		s.templates[t.Name] = t
+	}
+	return s
 }
 
-// NewServer creates a new server instance.
-func NewServer(conn *libvirt.Connect, storage StorageManager, vmNet *net.IPNet, dnsCli *DnsClient, xmlTemplate *template.Template) Server {
-	return Server{
-		conn:        conn,
-		storage:     storage,
-		vmNet:       vmNet,
-		dnsCli:      dnsCli,
-		xmlTemplate: xmlTemplate,
+// networkOf finds the network of an existing VM. VMs created before networks
+// were recorded in metadata are matched by their address.
+func (s Server) networkOf(vm *pb.VM) *Network {
+	if vm.Network != "" {
+		return s.networks[vm.Network]
+	}
+
+	ip := net.ParseIP(vm.Ip)
+	if ip == nil {
+		return nil
 	}
+	for _, n := range s.networks {
+		if n.Subnet.Contains(ip) {
+			return n
+		}
+	}
+	return nil
+}
+
+// parseDomain fetches and parses the persistent domain xml.
+func parseDomain(ctx context.Context, d libvirt.Domain) (libvirtDomain, error) {
+	return parseDomainXML(ctx, d, libvirt.DOMAIN_XML_INACTIVE)
 }
 
-// List is GRPC handler for List API.
-func (s Server) List(ctx context.Context, req *pb.ListVMRequest) (*pb.ListVMReply, error) {
-	domains, err := traceListAllDomains(ctx, s.conn)
+// parseLiveDomain fetches and parses the xml of the running domain.
+func parseLiveDomain(ctx context.Context, d libvirt.Domain) (libvirtDomain, error) {
+	return parseDomainXML(ctx, d, 0)
+}
+
+func parseDomainXML(ctx context.Context, d libvirt.Domain, flags libvirt.DomainXMLFlags) (libvirtDomain, error) {
+	domXML, err := traceDomainGetXMLDesc(ctx, d, flags)
 	if err != nil {
-		return nil, err
+		return libvirtDomain{}, err
 	}
 
-	repl := &pb.ListVMReply{}
-	repl.Vms = make([]*pb.VM, len(domains))
+	// TODO(farcaller): fails to load this
+	// metadataXML, err := d.GetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, MLPNamespace, libvirt.DOMAIN_AFFECT_LIVE)
 
-	for i, d := range domains {
-		name, err := traceDomainGetName(ctx, d)
-		if err != nil {
-			return nil, err
-		}
-
-		domXML, err := traceDomainGetXMLDesc(ctx, d)
-		if err != nil {
-			return nil, err
-		}
+	dom := libvirtDomain{}
+	err = xml.Unmarshal([]byte(domXML), &dom)
+	if err != nil {
+		return libvirtDomain{}, grpc.Errorf(codes.Internal, "failed to parse domain xml: %v", err)
+	}
+	return dom, nil
+}
 
-		// TODO(farcaller): fails to load this
-		// metadataXML, err := d.GetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, MLPNamespace, libvirt.DOMAIN_AFFECT_LIVE)
+// describeDomain collects the VM details from libvirt domain.
+func describeDomain(ctx context.Context, d libvirt.Domain) (*pb.VM, error) {
+	name, err := traceDomainGetName(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		dom := libvirtDomain{}
-		err = xml.Unmarshal([]byte(domXML), &dom)
-		if err != nil {
-			return nil, grpc.Errorf(codes.Internal, "failed to parse domain xml: %v", err)
-		}
+	dom, err := parseDomain(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		macs := extractMACs(dom)
-		if len(macs) != 1 {
-			glog.Warningf("strange mac count on %s: %v", name, macs)
-		}
+	state, err := traceDomainGetState(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		mac := macs[0]
-		ip := extractIP(dom)
-		if ip == "" {
-			glog.Warningf("failed to get ip for node %s", name)
-		}
+	macs := extractMACs(dom)
+	if len(macs) != 1 {
+		glog.Warningf("strange mac count on %s: %v", name, macs)
+	}
 
-		repl.Vms[i] = &pb.VM{
-			Name: name,
-			Ip:   ip,
-			Mac:  mac,
-		}
+	mac := ""
+	if len(macs) > 0 {
+		mac = macs[0]
+	}
+	ip := extractIP(dom)
+	if ip == "" {
+		glog.Warningf("failed to get ip for node %s", name)
 	}
 
-	return repl, nil
+	md := dom.Metadata.VMRegistry
+	return &pb.VM{
+		Name:        name,
+		Ip:          ip,
+		Mac:         mac,
+		Macs:        macs,
+		Mem:         dom.memoryBytes() >> 30,
+		Cores:       dom.VCPU.count(),
+		Size:        md.Size,
+		SourceImage: md.SourceImage,
+		State:       pb.VM_State(state),
+		Created:     md.Created,
+		Network:     md.Network,
+		Ipv6:        md.IPv6,
+		Labels:      md.labelMap(),
+		Flavor:      md.Flavor,
+		Template:    md.Template,
+	}, nil
 }
 
-// Find is GRPC handler for Find API.
-func (s Server) Find(ctx context.Context, req *pb.FindRequest) (*pb.VM, error) {
-	if req.FindBy == pb.FindRequest_UNSPECIFIED {
-		return nil, grpc.Errorf(codes.InvalidArgument, "search criteria not specified")
-	}
+// domainListing describes all the domains that could be described.
+type domainListing struct {
+	vms []*pb.VM
+	// skipped are the names of the domains that failed to be described.
+	skipped map[string]bool
+	// complete is false if a domain failed before even its name was known,
+	// so nothing can be concluded from a VM missing in vms.
+	complete bool
+}
 
-	domains, err := traceListAllDomains(ctx, s.conn)
+// describeAllDomains lists and describes all the domains. A domain that fails
+// to be described is logged and skipped, rather than failing the listing.
+func describeAllDomains(ctx context.Context, conn *libvirt.Connect) (domainListing, error) {
+	domains, err := traceListAllDomains(ctx, conn)
 	if err != nil {
-		return nil, err
+		return domainListing{}, err
 	}
 
+	l := domainListing{
+		vms:      make([]*pb.VM, 0, len(domains)),
+		skipped:  map[string]bool{},
+		complete: true,
+	}
 	for _, d := range domains {
-		name, err := traceDomainGetName(ctx, d)
-		if err != nil {
-			continue
-		}
-
-		domXML, err := traceDomainGetXMLDesc(ctx, d)
-		if err != nil {
+		vm, err := describeDomain(ctx, d)
+		if err == nil {
+			l.vms = append(l.vms, vm)
 			continue
 		}
 
-		dom := libvirtDomain{}
-		err = xml.Unmarshal([]byte(domXML), &dom)
-		if err != nil {
+		name, nerr := traceDomainGetName(ctx, d)
+		if nerr != nil {
+			glog.Warningf("skipping domain that can't be described: %v", err)
+			l.complete = false
 			continue
 		}
+		glog.Warningf("skipping %s, it can't be described: %v", name, err)
+		l.skipped[name] = true
+	}
+	return l, nil
+}
 
-		macs := extractMACs(dom)
-		if len(macs) != 1 {
-			glog.Warningf("strange mac count on %s: %v", name, macs)
-		}
-
-		mac := macs[0]
-		ip := extractIP(dom)
+// Find is GRPC handler for Find API.
+func (s Server) Find(ctx context.Context, req *pb.FindRequest) (*pb.VM, error) {
+	var vm *pb.VM
+	var ok bool
+
+	switch req.FindBy {
+	case pb.FindRequest_IP:
+		vm, ok = s.inventory.findByIP(req.Value)
+	case pb.FindRequest_MAC:
+		vm, ok = s.inventory.findByMAC(req.Value)
+	default:
+		return nil, grpc.Errorf(codes.InvalidArgument, "search criteria not specified")
+	}
 
-		repl := &pb.VM{
-			Name: name,
-			Ip:   ip,
-			Mac:  mac,
-		}
+	if !ok {
+		return nil, grpc.Errorf(codes.NotFound, "ip not found")
+	}
+	return vm, nil
+}
 
-		if req.FindBy == pb.FindRequest_IP && ip == req.Value {
-			return repl, nil
-		}
+// Get is GRPC handler for Get API.
+func (s Server) Get(ctx context.Context, req *pb.GetVMRequest) (*pb.VM, error) {
+	name := req.GetName()
+	if name == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
 
-		if req.FindBy == pb.FindRequest_MAC && mac == req.Value {
-			return repl, nil
-		}
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, grpc.Errorf(codes.NotFound, "ip not found")
+	return describeDomain(ctx, *d)
 }
 
 // Create is GRPC handler for Create API.
-func (s Server) Create(ctx context.Context, in *pb.CreateRequest) (*pb.VM, error) {
+func (s Server) Create(ctx context.Context, in *pb.CreateRequest) (*pb.Operation, error) {
 	name := in.GetName()
 	if name == "" {
 		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
 	}
+	if err := validateVMName(name); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	retried, err := s.operations.retried(in.GetRequestId(), pb.Operation_CREATE, name)
+	if err != nil {
+		return nil, err
+	}
+	if retried != nil {
+		return retried.snapshot(), nil
+	}
+
+	in, err = s.applyFlavor(in)
+	if err != nil {
+		return nil, err
+	}
+
 	mem := in.GetMem()
 	if mem == 0 {
 		return nil, grpc.Errorf(codes.InvalidArgument, "mem not specified")
@@ -201,115 +329,399 @@ func (s Server) Create(ctx context.Context, in *pb.CreateRequest) (*pb.VM, error
 	if sourceImage == "" {
 		return nil, grpc.Errorf(codes.InvalidArgument, "sourceImage not specified")
 	}
+	if err := validateLabels(in.GetLabels()); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if wantsCloudInit(in) {
+		if s.seeds == nil {
+			return nil, grpc.Errorf(codes.FailedPrecondition, "cloud-init seeds are not configured")
+		}
+		if err := validateCloudInit(in); err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+
+	network := s.defaultNetwork
+	if in.GetNetwork() != "" {
+		network = s.networks[in.GetNetwork()]
+		if network == nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "unknown network %s", in.GetNetwork())
+		}
+	}
+
+	tpl := s.defaultTemplate
+	if in.GetTemplate() != "" {
+		tpl = s.templates[in.GetTemplate()]
+		if tpl == nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "unknown template %s", in.GetTemplate())
+		}
+	}
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err == nil {
+		return s.createRetried(ctx, d, in)
+	}
+	if grpc.Code(err) != codes.NotFound {
+		return nil, err
+	}
+
+	o, err := s.operations.start(ctx, in.GetRequestId(), pb.Operation_CREATE, name, func(ctx context.Context, o *operation) (*pb.VM, error) {
+		return s.create(ctx, o, in, network, tpl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o.snapshot(), nil
+}
 
+// createRetried handles a create of an existing VM. If the VM was created
+// with the same request id, it's a retry of a create whose operation is no
+// longer known, e.g. after a restart, and it gets a finished operation.
+func (s Server) createRetried(ctx context.Context, d *libvirt.Domain, in *pb.CreateRequest) (*pb.Operation, error) {
+	name := in.GetName()
+	if in.GetRequestId() == "" {
+		return nil, grpc.Errorf(codes.AlreadyExists, "vm %s already exists", name)
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+	if config.Metadata.VMRegistry.RequestID != in.GetRequestId() {
+		return nil, grpc.Errorf(codes.AlreadyExists, "vm %s already exists", name)
+	}
+
+	o, err := s.operations.start(ctx, in.GetRequestId(), pb.Operation_CREATE, name, func(ctx context.Context, o *operation) (*pb.VM, error) {
+		return describeDomain(ctx, *d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o.snapshot(), nil
+}
+
+// create runs the steps of a create operation.
+func (s Server) create(ctx context.Context, o *operation, in *pb.CreateRequest, network *Network, tpl *DomainTemplate) (*pb.VM, error) {
+	name := in.GetName()
+	size := in.GetSize()
+	sourceImage := in.GetSourceImage()
+
+	sg := newSaga("create " + name)
+	defer sg.rollbackUnlessCommitted(ctx)
+
+	if err := o.step(ctx, "storage"); err != nil {
+		return nil, err
+	}
 	err := s.storage.CreateStorage(ctx, name, size, sourceImage)
+	if grpc.Code(err) == codes.AlreadyExists {
+		return nil, err
+	}
 	if err != nil {
 		return nil, grpc.Errorf(codes.Internal, "failed to create storage: %v", err)
 	}
+	sg.onRollback("storage", func(ctx context.Context) error {
+		return s.storage.RemoveStorage(ctx, name)
+	})
+
+	if err := o.step(ctx, "ip"); err != nil {
+		return nil, err
+	}
+	ip, err := network.ipam.Allocate(name)
+	if err != nil {
+		return nil, grpc.Errorf(codes.ResourceExhausted, "failed to allocate ip in %s: %v", network.Name, err)
+	}
+	sg.onRollback("ip allocation", func(ctx context.Context) error {
+		return network.ipam.Release(ip)
+	})
+
+	mac, ip6, err := s.allocateMAC(network, name)
+	if err != nil {
+		return nil, grpc.Errorf(codes.ResourceExhausted, "failed to allocate mac and ipv6 in %s: %v", network.Name, err)
+	}
+	sg.onRollback("ipv6 allocation", func(ctx context.Context) error {
+		return network.releaseIPv6(ip6)
+	})
+	ip6str := ""
+	if ip6 != nil {
+		ip6str = ip6.String()
+	}
 
-	var ip net.IP
-	for i := 0; i < 10; i++ {
-		tryip := generateIPv4(s.vmNet)
-		searchReq := &pb.FindRequest{
-			FindBy: pb.FindRequest_IP,
-			Value:  tryip.String(),
+	seedPath := ""
+	if wantsCloudInit(in) {
+		if err := o.step(ctx, "seed"); err != nil {
+			return nil, err
 		}
-		_, err := s.Find(ctx, searchReq)
-		code := grpc.Code(err)
-		if code == codes.NotFound {
-			ip = tryip
-			break
+		seedPath, err = s.createSeed(ctx, in, network, mac.String(), ip.String(), ip6str)
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "failed to create cloud-init seed: %v", err)
 		}
+		sg.onRollback("cloud-init seed", func(ctx context.Context) error {
+			return s.seeds.RemoveSeed(ctx, name)
+		})
+	}
+
+	if err := o.step(ctx, "define"); err != nil {
+		return nil, err
 	}
-	if len(ip) == 0 {
-		return nil, grpc.Errorf(codes.Unavailable, "failed to generate a new ip after 10 attempts")
+	md := vmMetadata{
+		IP:          ip.String(),
+		IPv6:        ip6str,
+		SourceImage: sourceImage,
+		Size:        size,
+		Created:     time.Now().Unix(),
+		Network:     network.Name,
+		Flavor:      in.GetFlavor(),
+		Template:    tpl.Name,
+		RequestID:   in.GetRequestId(),
 	}
+	md.setLabels(in.GetLabels())
 
-	var domBuffer bytes.Buffer
-	s.xmlTemplate.Execute(&domBuffer, struct {
-		Name     string
-		Memory   uint64
-		Cores    uint32
-		DiskPath string
-		IP       string
-	}{
+	domXML, err := buildDomainXML(tpl, domainSpec{
 		Name:     name,
 		Memory:   in.GetMem(),
 		Cores:    in.GetCores(),
 		DiskPath: s.storage.StorageBlockDevice(name),
 		IP:       ip.String(),
+		IPv6:     ip6str,
+		MAC:      mac.String(),
+		SeedPath: seedPath,
+		Metadata: md,
+		Network:  network,
 	})
-	domXML := domBuffer.String()
+	if err != nil {
+		return nil, err
+	}
 
 	d, err := s.conn.DomainDefineXML(domXML)
 	if err != nil {
 		return nil, grpc.Errorf(codes.Internal, "failed to define vm: %v", err)
 	}
+	sg.onRollback("domain definition", func(ctx context.Context) error {
+		return traceDomainAction(ctx, "UndefineFlags", func() error { return undefineDomain(d) })
+	})
 
+	if err := o.step(ctx, "start"); err != nil {
+		return nil, err
+	}
 	err = d.Create()
 	if err != nil {
 		return nil, grpc.Errorf(codes.Internal, "failed to create vm: %v", err)
 	}
+	sg.onRollback("domain start", func(ctx context.Context) error {
+		return traceDomainAction(ctx, "Destroy", d.Destroy)
+	})
+
+	if err := o.step(ctx, "dns"); err != nil {
+		return nil, err
+	}
+	err = s.publishDNS(sg, network, name, ip.String(), ip6str)
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to update dns records: %v", err)
+	}
 
-	err = s.dnsCli.Add(name, ip.String())
+	vm, err := describeDomain(ctx, *d)
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to update dns record: %v", err)
+		glog.Warningf("failed to describe new vm %s: %v", name, err)
+		vm = &pb.VM{
+			Name: name,
+			Ip:   ip.String(),
+			Ipv6: ip6str,
+			Mac:  mac.String(),
+		}
 	}
 
-	return &pb.VM{
-		Name: name,
-		Ip:   ip.String(),
-		Mac:  "FIXME",
-	}, nil
+	sg.commit()
+	s.inventory.put(vm)
+	return vm, nil
 }
 
 // Destroy is GRPC handler for Destroy API.
-func (s Server) Destroy(ctx context.Context, in *pb.DestroyRequest) (*pb.DestroyReply, error) {
+func (s Server) Destroy(ctx context.Context, in *pb.DestroyRequest) (*pb.Operation, error) {
 	name := in.GetName()
 	if name == "" {
 		return nil, grpc.Errorf(codes.InvalidArgument, "name not specified")
 	}
 
-	dom, err := traceGetDomainByName(ctx, s.conn, name)
+	retried, err := s.operations.retried(in.GetRequestId(), pb.Operation_DESTROY, name)
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to lookup vm: %v", err)
+		return nil, err
+	}
+	if retried != nil {
+		return retried.snapshot(), nil
 	}
 
-	domXML, err := traceDomainGetXMLDesc(ctx, *dom)
+	dom, err := traceGetDomainByName(ctx, s.conn, name)
+	if grpc.Code(err) == codes.NotFound {
+		// nothing left to do, e.g. a retry of a destroy that went through.
+		o, err := s.operations.start(ctx, in.GetRequestId(), pb.Operation_DESTROY, name, func(ctx context.Context, o *operation) (*pb.VM, error) {
+			return nil, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return o.snapshot(), nil
+	}
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to get vm xml: %v", err)
+		return nil, grpc.Errorf(codes.Internal, "failed to lookup vm: %v", err)
 	}
 
-	domData := libvirtDomain{}
-	err = xml.Unmarshal([]byte(domXML), &domData)
+	vm, err := describeDomain(ctx, *dom)
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to parse domain xml: %v", err)
+		return nil, grpc.Errorf(codes.Internal, "failed to get vm details: %v", err)
 	}
 
-	ip := extractIP(domData)
+	ip := vm.Ip
 	if ip == "" {
 		return nil, grpc.Errorf(codes.Internal, "failed to get ip for node %s", name)
 	}
 
-	err = s.dnsCli.Remove(name, ip)
+	network := s.networkOf(vm)
+	if network == nil {
+		return nil, grpc.Errorf(codes.Internal, "failed to find network of %s", name)
+	}
+
+	o, err := s.operations.start(ctx, in.GetRequestId(), pb.Operation_DESTROY, name, func(ctx context.Context, o *operation) (*pb.VM, error) {
+		// the metadata is read with the VM locked, so disks and snapshots
+		// added meanwhile are cleaned up too.
+		defer s.locks.lock(name)()
+
+		config, err := parseDomain(ctx, *dom)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.destroy(ctx, o, dom, vm, network, config.Metadata.VMRegistry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o.snapshot(), nil
+}
+
+// undefineDomain removes the domain definition along with its nvram, managed
+// save image and snapshot metadata, which a plain undefine refuses to drop,
+// e.g. for uefi templates.
+func undefineDomain(d *libvirt.Domain) error {
+	return d.UndefineFlags(libvirt.DOMAIN_UNDEFINE_NVRAM | libvirt.DOMAIN_UNDEFINE_MANAGED_SAVE | libvirt.DOMAIN_UNDEFINE_SNAPSHOTS_METADATA)
+}
+
+// destroy runs the steps of a destroy operation.
+func (s Server) destroy(ctx context.Context, o *operation, dom *libvirt.Domain, vm *pb.VM, network *Network, md vmMetadata) error {
+	name := vm.Name
+	ip := vm.Ip
+
+	if err := o.step(ctx, "dns"); err != nil {
+		return err
+	}
+	err := s.unpublishDNS(network, name, ip, vm.Ipv6)
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to update dns record: %v", err)
+		return grpc.Errorf(codes.Internal, "failed to update dns records: %v", err)
 	}
 
+	if err := o.step(ctx, "domain"); err != nil {
+		return err
+	}
 	err = dom.Destroy()
 	if err != nil {
 		glog.Infof("failed to destroy vm: %v, continuing with undefining", err)
 	}
 
-	err = dom.Undefine()
+	err = undefineDomain(dom)
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to undefine vm: %v", err)
+		return grpc.Errorf(codes.Internal, "failed to undefine vm: %v", err)
 	}
+	s.inventory.remove(name)
 
+	if err := o.step(ctx, "storage"); err != nil {
+		return err
+	}
+	s.removeSnapshots(ctx, name, md)
 	err = s.storage.RemoveStorage(ctx, name)
 	if err != nil {
-		return nil, grpc.Errorf(codes.Internal, "failed to remove vm storage: %v", err)
+		return grpc.Errorf(codes.Internal, "failed to remove vm storage: %v", err)
+	}
+	err = s.removeDataDisks(ctx, name, md.Disks)
+	if err != nil {
+		return grpc.Errorf(codes.Internal, "failed to remove vm storage: %v", err)
+	}
+	if s.seeds != nil {
+		err = s.seeds.RemoveSeed(ctx, name)
+		if err != nil {
+			glog.Warningf("failed to remove cloud-init seed of %s: %v", name, err)
+		}
+	}
+
+	if err := o.step(ctx, "ip"); err != nil {
+		return err
+	}
+	err = network.ipam.Release(net.ParseIP(ip))
+	if err != nil {
+		glog.Warningf("failed to release ip %s of %s: %v", ip, name, err)
+	}
+
+	err = network.releaseIPv6(net.ParseIP(vm.Ipv6))
+	if err != nil {
+		glog.Warningf("failed to release ipv6 %s of %s: %v", vm.Ipv6, name, err)
+	}
+
+	return nil
+}
+
+// SyncIPAllocations makes ip allocations match the existing domains: addresses
+// of all the known VMs are reserved, and allocations of VMs that no longer
+// exist are released. Domains that can't be described keep their allocations.
+// It's meant to be called on startup.
+func (s Server) SyncIPAllocations(ctx context.Context) error {
+	listing, err := describeAllDomains(ctx, s.conn)
+	if err != nil {
+		return err
+	}
+
+	owners := map[*Network]map[string]bool{}
+	for _, n := range s.networks {
+		owners[n] = map[string]bool{}
+	}
+
+	for _, vm := range listing.vms {
+		network := s.networkOf(vm)
+		ip := net.ParseIP(vm.Ip)
+		if network == nil || ip == nil {
+			glog.Warningf("vm %s (%s) is not in any known network", vm.Name, vm.Ip)
+			continue
+		}
+		owners[network][vm.Name] = true
+
+		if err := network.ipam.Reserve(ip, vm.Name); err != nil {
+			glog.Warningf("failed to reserve ip of %s: %v", vm.Name, err)
+		}
+
+		if ip6 := net.ParseIP(vm.Ipv6); ip6 != nil {
+			if err := network.reserveIPv6(ip6, vm.Name); err != nil {
+				glog.Warningf("failed to reserve ipv6 of %s: %v", vm.Name, err)
+			}
+		}
+	}
+
+	if !listing.complete {
+		glog.Warningf("not releasing ips of missing vms, some domains couldn't be described")
+		return nil
+	}
+
+	for network, names := range owners {
+		for _, ipam := range []IPAllocator{network.ipam, network.ipam6} {
+			if ipam == nil {
+				continue
+			}
+			for ip, owner := range ipam.Allocations() {
+				if names[owner] || listing.skipped[owner] {
+					continue
+				}
+				glog.Infof("releasing ip %s of missing vm %s in %s", ip, owner, network.Name)
+				if err := ipam.Release(net.ParseIP(ip)); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
-	return &pb.DestroyReply{}, nil
+	return nil
 }
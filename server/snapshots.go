@@ -0,0 +1,357 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/golang/glog"
+	libvirt "github.com/libvirt/libvirt-go"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+const maxSnapshots = 32
+
+func describeSnapshot(snap vmSnapshot) *pb.Snapshot {
+	return &pb.Snapshot{
+		Name:     snap.Name,
+		Created:  snap.Created,
+		Disks:    snap.Disks,
+		Quiesced: snap.Quiesced,
+	}
+}
+
+// snapshotVolumes returns the volumes covered by a snapshot: the boot volume
+// and the data disks that are still attached.
+func snapshotVolumes(vmName string, snap vmSnapshot, disks []vmDisk) []string {
+	attached := map[string]bool{}
+	for _, disk := range disks {
+		attached[disk.Name] = true
+	}
+
+	volumes := []string{vmName}
+	for _, disk := range snap.Disks {
+		if attached[disk] {
+			volumes = append(volumes, diskStorageName(vmName, disk))
+		}
+	}
+	return volumes
+}
+
+// findSnapshot returns the index of the named snapshot in the metadata.
+func findSnapshot(md vmMetadata, vmName string, snapshot string) (int, error) {
+	for i, snap := range md.Snapshots {
+		if snap.Name == snapshot {
+			return i, nil
+		}
+	}
+	return -1, grpc.Errorf(codes.NotFound, "snapshot %s of %s not found", snapshot, vmName)
+}
+
+// snapshotDomain looks up the domain and parses its persistent definition for
+// the snapshot handlers.
+func (s Server) snapshotDomain(ctx context.Context, name string, snapshot string) (*libvirt.Domain, vmMetadata, error) {
+	if name == "" {
+		return nil, vmMetadata{}, grpc.Errorf(codes.InvalidArgument, "name not specified")
+	}
+	if !diskNameRe.MatchString(snapshot) {
+		return nil, vmMetadata{}, grpc.Errorf(codes.InvalidArgument, "invalid snapshot name %q", snapshot)
+	}
+
+	d, err := traceGetDomainByName(ctx, s.conn, name)
+	if err != nil {
+		return nil, vmMetadata{}, err
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, vmMetadata{}, err
+	}
+	return d, config.Metadata.VMRegistry, nil
+}
+
+// checkSnapshotState fails unless the storage can snapshot the VM in its
+// current state. qemu holds a lock on the qcow2 image of a running VM, so
+// qemu-img can only change its snapshots while the VM is shut off.
+func (s Server) checkSnapshotState(name string, state libvirt.DomainState) error {
+	if s.storage.StorageFormat() == "qcow2" && state != libvirt.DOMAIN_SHUTOFF {
+		return grpc.Errorf(codes.FailedPrecondition, "%s must be shut off to change its snapshots", name)
+	}
+	return nil
+}
+
+// freezeFilesystems flushes and freezes the guest file systems through the
+// qemu guest agent. Returns false if that's not possible, e.g. the guest
+// doesn't run the agent.
+func freezeFilesystems(ctx context.Context, d *libvirt.Domain, name string) bool {
+	err := traceDomainAction(ctx, "FSFreeze", func() error {
+		return d.FSFreeze(nil, 0)
+	})
+	if err != nil {
+		glog.Infof("failed to freeze file systems of %s, its snapshot is only crash consistent: %v", name, err)
+		return false
+	}
+	return true
+}
+
+// CreateSnapshot is GRPC handler for CreateSnapshot API. It snapshots the boot
+// volume and all the data disks. A running VM is paused meanwhile, so that
+// all the volumes are taken at the same point of guest time, and its file
+// systems are frozen first if the guest agent allows. Without the agent the
+// snapshot is only crash consistent. File storage needs the VM shut off
+// instead.
+func (s Server) CreateSnapshot(ctx context.Context, in *pb.CreateSnapshotRequest) (*pb.Snapshot, error) {
+	name := in.GetName()
+	defer s.locks.lock(name)()
+
+	d, md, err := s.snapshotDomain(ctx, name, in.GetSnapshot())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateVMName(name); err != nil {
+		return nil, grpc.Errorf(codes.FailedPrecondition, "vm can't have snapshots: %v", err)
+	}
+	if _, err := findSnapshot(md, name, in.GetSnapshot()); err == nil {
+		return nil, grpc.Errorf(codes.AlreadyExists, "snapshot %s of %s already exists", in.GetSnapshot(), name)
+	}
+	if len(md.Snapshots) >= maxSnapshots {
+		return nil, grpc.Errorf(codes.ResourceExhausted, "%s already has %d snapshots", name, len(md.Snapshots))
+	}
+
+	state, err := traceDomainGetState(ctx, *d)
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+	if err := s.checkSnapshotState(name, state); err != nil {
+		return nil, err
+	}
+	quiesced := false
+	if state == libvirt.DOMAIN_RUNNING {
+		// thawing needs the guest running, so it's deferred before resuming.
+		quiesced = freezeFilesystems(ctx, d, name)
+		if quiesced {
+			defer func() {
+				err := traceDomainAction(ctx, "FSThaw", func() error {
+					return d.FSThaw(nil, 0)
+				})
+				if err != nil {
+					glog.Errorf("failed to thaw file systems of %s after snapshot: %v", name, err)
+				}
+			}()
+		}
+
+		err = traceDomainAction(ctx, "Suspend", d.Suspend)
+		if err != nil {
+			return nil, grpc.Errorf(libvirtErrorCode(err), "failed to pause %s: %v", name, err)
+		}
+		defer func() {
+			if err := traceDomainAction(ctx, "Resume", d.Resume); err != nil {
+				glog.Errorf("failed to resume %s after snapshot: %v", name, err)
+			}
+		}()
+	}
+
+	snap := vmSnapshot{
+		Name:     in.GetSnapshot(),
+		Created:  time.Now().Unix(),
+		Quiesced: quiesced,
+		Disks:    []string{},
+	}
+	for _, disk := range md.Disks {
+		if !disk.Detaching {
+			snap.Disks = append(snap.Disks, disk.Name)
+		}
+	}
+
+	sg := newSaga("snapshot " + name + "@" + snap.Name)
+	defer sg.rollbackUnlessCommitted(ctx)
+
+	for _, volume := range snapshotVolumes(name, snap, md.Disks) {
+		volume := volume
+		err = s.storage.CreateSnapshot(ctx, volume, snap.Name)
+		if grpc.Code(err) == codes.Unimplemented {
+			return nil, err
+		}
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "failed to snapshot %s: %v", volume, err)
+		}
+		sg.onRollback("snapshot of "+volume, func(ctx context.Context) error {
+			return s.storage.RemoveSnapshot(ctx, volume, snap.Name)
+		})
+	}
+
+	md.Snapshots = append(md.Snapshots, snap)
+	err = traceDomainSetMetadata(ctx, *d, md)
+	if err != nil {
+		return nil, err
+	}
+
+	sg.commit()
+	return describeSnapshot(snap), nil
+}
+
+// RevertSnapshot is GRPC handler for RevertSnapshot API. The VM must be shut
+// off. Data disks attached after the snapshot are left as they are.
+func (s Server) RevertSnapshot(ctx context.Context, in *pb.RevertSnapshotRequest) (*pb.Snapshot, error) {
+	name := in.GetName()
+	defer s.locks.lock(name)()
+
+	d, md, err := s.snapshotDomain(ctx, name, in.GetSnapshot())
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findSnapshot(md, name, in.GetSnapshot())
+	if err != nil {
+		return nil, err
+	}
+	snap := md.Snapshots[i]
+
+	state, err := traceDomainGetState(ctx, *d)
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+	if state != libvirt.DOMAIN_SHUTOFF {
+		return nil, grpc.Errorf(codes.FailedPrecondition, "%s must be shut off to revert a snapshot", name)
+	}
+
+	// reverted volumes can't be brought back, so a failure leaves the VM
+	// partly reverted, and the error says which volume it stopped at.
+	for _, volume := range snapshotVolumes(name, snap, md.Disks) {
+		err = s.storage.RevertSnapshot(ctx, volume, snap.Name)
+		if grpc.Code(err) == codes.Unimplemented {
+			return nil, err
+		}
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "failed to revert %s: %v", volume, err)
+		}
+	}
+	return describeSnapshot(snap), nil
+}
+
+// DeleteSnapshot is GRPC handler for DeleteSnapshot API. File storage needs
+// the VM shut off.
+func (s Server) DeleteSnapshot(ctx context.Context, in *pb.DeleteSnapshotRequest) (*pb.Snapshot, error) {
+	name := in.GetName()
+	defer s.locks.lock(name)()
+
+	d, md, err := s.snapshotDomain(ctx, name, in.GetSnapshot())
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findSnapshot(md, name, in.GetSnapshot())
+	if err != nil {
+		return nil, err
+	}
+	snap := md.Snapshots[i]
+
+	state, err := traceDomainGetState(ctx, *d)
+	if err != nil {
+		return nil, grpc.Errorf(libvirtErrorCode(err), "failed to get state of %s: %v", name, err)
+	}
+	if err := s.checkSnapshotState(name, state); err != nil {
+		return nil, err
+	}
+
+	for _, volume := range snapshotVolumes(name, snap, md.Disks) {
+		err = s.storage.RemoveSnapshot(ctx, volume, snap.Name)
+		if grpc.Code(err) == codes.Unimplemented {
+			return nil, err
+		}
+		if err != nil {
+			return nil, grpc.Errorf(codes.Internal, "failed to remove snapshot of %s: %v", volume, err)
+		}
+	}
+
+	md.Snapshots = append(md.Snapshots[:i], md.Snapshots[i+1:]...)
+	err = traceDomainSetMetadata(ctx, *d, md)
+	if err != nil {
+		return nil, err
+	}
+	return describeSnapshot(snap), nil
+}
+
+// ListSnapshots is GRPC handler for ListSnapshots API.
+func (s Server) ListSnapshots(ctx context.Context, in *pb.ListSnapshotsRequest) (*pb.ListSnapshotsReply, error) {
+	d, err := traceGetDomainByName(ctx, s.conn, in.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseDomain(ctx, *d)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*pb.Snapshot{}
+	for _, snap := range config.Metadata.VMRegistry.Snapshots {
+		snapshots = append(snapshots, describeSnapshot(snap))
+	}
+	return &pb.ListSnapshotsReply{Snapshots: snapshots}, nil
+}
+
+// removeDiskSnapshots removes the snapshots of a data disk whose volume is
+// about to be removed, and drops the disk from the snapshots in place.
+func (s Server) removeDiskSnapshots(ctx context.Context, vmName string, disk string, snapshots []vmSnapshot) error {
+	for i, snap := range snapshots {
+		disks := []string{}
+		for _, d := range snap.Disks {
+			if d != disk {
+				disks = append(disks, d)
+				continue
+			}
+			if err := s.storage.RemoveSnapshot(ctx, diskStorageName(vmName, disk), snap.Name); err != nil {
+				return fmt.Errorf("failed to remove snapshot %s of disk %s: %v", snap.Name, disk, err)
+			}
+		}
+		snapshots[i].Disks = disks
+	}
+	return nil
+}
+
+// removeSnapshots removes the snapshots of a VM being destroyed, apart from
+// the ones of retained disks. Failures are only logged, most storages remove
+// the snapshots along with the volumes anyway.
+func (s Server) removeSnapshots(ctx context.Context, vmName string, md vmMetadata) {
+	keep := map[string]bool{}
+	for _, disk := range md.Disks {
+		if disk.Retain {
+			keep[diskStorageName(vmName, disk.Name)] = true
+		}
+	}
+
+	for _, snap := range md.Snapshots {
+		for _, volume := range snapshotVolumes(vmName, snap, md.Disks) {
+			if keep[volume] {
+				continue
+			}
+			if err := s.storage.RemoveSnapshot(ctx, volume, snap.Name); err != nil {
+				glog.Warningf("failed to remove snapshot %s of %s: %v", snap.Name, volume, err)
+			}
+		}
+	}
+}
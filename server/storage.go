@@ -21,6 +21,8 @@ package server
 import (
 	"flag"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"golang.org/x/net/context"
 
@@ -33,10 +35,15 @@ var (
 	lvmMirrors = flag.Int("lvm-mirrors", 0, "lvm mirrors count, 0 to disable")
 )
 
+// LVMStorage keeps VM disks as logical volumes managed through lvmd. What
+// lvmd has no api for is done by running lvm on the local host, so that
+// needs vmregistry to run on the host of the volume group.
 type LVMStorage struct {
 	client   pb.LVMClient
 	vg       string
 	lvmToken string
+	lvm      string
+	runner   CommandRunner
 }
 
 func newLVMClient(lvmAddress string, lvmCA string) (pb.LVMClient, error) {
@@ -48,13 +55,15 @@ func newLVMClient(lvmAddress string, lvmCA string) (pb.LVMClient, error) {
 	return cli, nil
 }
 
-func NewLVMStorage(lvmAddress string, lvmCA string, vg string, lvmToken string) (StorageManager, error) {
+// NewLVMStorage creates a storage in the volume group vg. lvm is the lvm
+// binary to run locally.
+func NewLVMStorage(lvmAddress string, lvmCA string, vg string, lvmToken string, lvm string, runner CommandRunner) (StorageManager, error) {
 	client, err := newLVMClient(lvmAddress, lvmCA)
 	if err != nil {
 		return nil, err
 	}
 
-	return LVMStorage{client, vg, lvmToken}, nil
+	return LVMStorage{client: client, vg: vg, lvmToken: lvmToken, lvm: lvm, runner: runner}, nil
 }
 
 func (s LVMStorage) authContext(ctx context.Context) context.Context {
@@ -79,7 +88,11 @@ func (s LVMStorage) CreateStorage(ctx context.Context, name string, size uint64,
 		return err
 	}
 
-	s.client.CloneLV(ctx, &pb.CloneLVRequest{
+	if sourceImage == "" {
+		return nil
+	}
+
+	_, err = s.client.CloneLV(ctx, &pb.CloneLVRequest{
 		SourceName: sourceImage,
 		DestName:   s.StorageBlockDevice(name),
 	})
@@ -101,6 +114,57 @@ func (s LVMStorage) RemoveStorage(ctx context.Context, name string) error {
 	return err
 }
 
+func (s LVMStorage) run(ctx context.Context, args ...string) ([]byte, error) {
+	return s.runner.Run(ctx, s.lvm, args...)
+}
+
+// lvPath is the vg/lv name the lvm tools take.
+func (s LVMStorage) lvPath(name string) string {
+	return s.vg + "/" + name
+}
+
+// ResizeStorage runs lvresize, lvmd has no api to resize volumes. The size is
+// rounded up to the extent size.
+func (s LVMStorage) ResizeStorage(ctx context.Context, name string, size uint64) error {
+	_, err := s.run(ctx, "lvresize", "--force", "--size", strconv.FormatUint(size, 10)+"b", s.lvPath(name))
+	return err
+}
+
+// CreateSnapshot isn't supported, lvmd has no api to snapshot volumes.
+// snapshotLV is the name of the snapshot volume. VM, disk and snapshot names
+// can't contain '+', so it can't clash with other volumes.
+func (s LVMStorage) snapshotLV(name string, snapshot string) string {
+	return name + "+" + snapshot
+}
+
+// CreateSnapshot creates a thick snapshot volume as large as the volume, so it
+// can't run out of space even if the whole volume is rewritten.
+func (s LVMStorage) CreateSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "lvcreate", "--snapshot", "--extents", "100%ORIGIN", "--name", s.snapshotLV(name, snapshot), s.lvPath(name))
+	return err
+}
+
+// RevertSnapshot merges the snapshot back into the volume, which consumes
+// the snapshot, and takes it again so that it's kept.
+func (s LVMStorage) RevertSnapshot(ctx context.Context, name string, snapshot string) error {
+	if _, err := s.run(ctx, "lvconvert", "--merge", s.lvPath(s.snapshotLV(name, snapshot))); err != nil {
+		return err
+	}
+	return s.CreateSnapshot(ctx, name, snapshot)
+}
+
+func (s LVMStorage) RemoveSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "lvremove", "--force", s.lvPath(s.snapshotLV(name, snapshot)))
+	if err != nil && strings.Contains(err.Error(), "Failed to find logical volume") {
+		return nil
+	}
+	return err
+}
+
 func (s LVMStorage) StorageBlockDevice(name string) string {
 	return fmt.Sprintf("/dev/%s/%s", s.vg, name)
 }
+
+func (s LVMStorage) StorageFormat() string {
+	return "raw"
+}
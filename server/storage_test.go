@@ -0,0 +1,77 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func newTestLVMStorage(r *fakeRunner) LVMStorage {
+	return LVMStorage{vg: "vg0", lvm: "lvm", runner: r}
+}
+
+func TestLVMResizeStorage(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestLVMStorage(r)
+
+	if err := s.ResizeStorage(context.Background(), "vm1", 10737418240); err != nil {
+		t.Fatalf("ResizeStorage failed: %v", err)
+	}
+	checkCalls(t, r, "lvm lvresize --force --size 10737418240b vg0/vm1")
+}
+
+func TestLVMSnapshots(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestLVMStorage(r)
+	ctx := context.Background()
+
+	if err := s.CreateSnapshot(ctx, "vm1_data", "before-upgrade"); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if err := s.RevertSnapshot(ctx, "vm1_data", "before-upgrade"); err != nil {
+		t.Fatalf("RevertSnapshot failed: %v", err)
+	}
+	if err := s.RemoveSnapshot(ctx, "vm1_data", "before-upgrade"); err != nil {
+		t.Fatalf("RemoveSnapshot failed: %v", err)
+	}
+	checkCalls(t, r,
+		"lvm lvcreate --snapshot --extents 100%ORIGIN --name vm1_data+before-upgrade vg0/vm1_data",
+		"lvm lvconvert --merge vg0/vm1_data+before-upgrade",
+		"lvm lvcreate --snapshot --extents 100%ORIGIN --name vm1_data+before-upgrade vg0/vm1_data",
+		"lvm lvremove --force vg0/vm1_data+before-upgrade",
+	)
+}
+
+func TestLVMRemoveMissingSnapshot(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestLVMStorage(r)
+
+	r.errors["lvm lvremove --force vg0/vm1+gone"] = errors.New(`lvm lvremove --force vg0/vm1+gone failed: exit status 5: Failed to find logical volume "vg0/vm1+gone"`)
+	if err := s.RemoveSnapshot(context.Background(), "vm1", "gone"); err != nil {
+		t.Errorf("RemoveSnapshot of a missing snapshot failed: %v", err)
+	}
+
+	r.errors["lvm lvremove --force vg0/vm1+busy"] = errors.New("lvm lvremove --force vg0/vm1+busy failed: exit status 5: Logical volume vg0/vm1+busy in use.")
+	if err := s.RemoveSnapshot(context.Background(), "vm1", "busy"); err == nil {
+		t.Errorf("RemoveSnapshot of a busy snapshot succeeded")
+	}
+}
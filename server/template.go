@@ -0,0 +1,135 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/google/vmregistry/api"
+)
+
+// DomainTemplate is a named libvirt domain xml template new VMs are defined
+// from.
+type DomainTemplate struct {
+	Name string
+	Path string
+
+	tpl *template.Template
+}
+
+// LoadDomainTemplate reads and validates a single template.
+func LoadDomainTemplate(name string, path string) (*DomainTemplate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tpl, err := template.New(name).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %v", name, err)
+	}
+
+	t := &DomainTemplate{Name: name, Path: path, tpl: tpl}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadDomainTemplates loads all the *.xml files of the directory, named after
+// the file name without the extension.
+func LoadDomainTemplates(dir string) ([]*DomainTemplate, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.xml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	templates := []*DomainTemplate{}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".xml")
+		t, err := LoadDomainTemplate(name, path)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// validate builds a sample domain, to catch templates that don't render to a
+// domain vmregistry can manage: it must keep all of the metadata, and attach
+// the VM disk and an interface. The sample metadata has every field set, so a
+// template that writes out only some of them fails.
+func (t *DomainTemplate) validate() error {
+	_, subnet, _ := net.ParseCIDR("192.0.2.0/24")
+	_, err := buildDomainXML(t, domainSpec{
+		Name:     "vmregistry-template-check",
+		Memory:   1,
+		Cores:    1,
+		DiskPath: "/dev/vmregistry/vmregistry-template-check",
+		IP:       "192.0.2.10",
+		MAC:      "52:54:00:00:00:01",
+		Metadata: vmMetadata{
+			IP:          "192.0.2.10",
+			SourceImage: "image",
+			Size:        1 << 30,
+			Created:     1,
+			Network:     "default",
+			Flavor:      "flavor",
+			Template:    t.Name,
+			RequestID:   "request",
+			Labels:      []vmLabel{{Key: "role", Value: "check"}},
+		},
+		Network: &Network{
+			Name:           "default",
+			Subnet:         subnet,
+			Gateway:        net.ParseIP("192.0.2.1"),
+			Bridge:         "br0",
+			LibvirtNetwork: "default",
+		},
+	})
+	if err != nil {
+		return errors.New(grpc.ErrorDesc(err))
+	}
+	return nil
+}
+
+// ListTemplates is GRPC handler for ListTemplates API.
+func (s Server) ListTemplates(ctx context.Context, in *pb.ListTemplatesRequest) (*pb.ListTemplatesReply, error) {
+	templates := []*pb.DomainTemplate{}
+	for _, t := range s.templates {
+		templates = append(templates, &pb.DomainTemplate{
+			Name:      t.Name,
+			IsDefault: t == s.defaultTemplate,
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return &pb.ListTemplatesReply{Templates: templates}, nil
+}
@@ -0,0 +1,70 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+const testDomainTemplate = `<domain type="kvm">
+  <name>{{.Name}}</name>
+  <memory unit="GiB">{{.Memory}}</memory>
+  <vcpu>{{.Cores}}</vcpu>
+  <metadata>
+    METADATA
+  </metadata>
+  <devices>
+    <disk type="block" device="disk">
+      <source dev="{{.DiskPath}}"/>
+      <target dev="vda" bus="virtio"/>
+    </disk>
+    <interface type="bridge">
+      <mac address="{{.MAC}}"/>
+      <source bridge="{{.Bridge}}"/>
+    </interface>
+  </devices>
+</domain>`
+
+func TestDomainTemplateValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		wantErr  bool
+	}{
+		{name: "metadata", metadata: "{{.Metadata}}"},
+		{name: "no metadata", metadata: "", wantErr: true},
+		{name: "only ip", metadata: `<vmregistry xmlns="` + vmMetadataNamespace + `"><ip>{{.IP}}</ip></vmregistry>`, wantErr: true},
+		{name: "ip and network", metadata: `<vmregistry xmlns="` + vmMetadataNamespace + `"><ip>{{.IP}}</ip><network>{{.Network}}</network></vmregistry>`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		text := strings.Replace(testDomainTemplate, "METADATA", tt.metadata, 1)
+		tpl, err := template.New(tt.name).Parse(text)
+		if err != nil {
+			t.Fatalf("%s: failed to parse template: %v", tt.name, err)
+		}
+
+		err = (&DomainTemplate{Name: tt.name, tpl: tpl}).validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validate() = %v, want error: %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
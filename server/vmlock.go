@@ -0,0 +1,64 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"sync"
+)
+
+// vmLocks serializes the changes to the definition of each VM. Handlers read
+// the whole vmregistry metadata and write it back, so concurrent changes to
+// the same VM would otherwise drop each other's updates.
+type vmLocks struct {
+	mu    sync.Mutex
+	locks map[string]*vmLock
+}
+
+type vmLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newVMLocks() *vmLocks {
+	return &vmLocks{locks: map[string]*vmLock{}}
+}
+
+// lock blocks until the VM is free and returns the function to release it.
+func (l *vmLocks) lock(name string) func() {
+	l.mu.Lock()
+	vl, ok := l.locks[name]
+	if !ok {
+		vl = &vmLock{}
+		l.locks[name] = vl
+	}
+	vl.refs++
+	l.mu.Unlock()
+
+	vl.Lock()
+	return func() {
+		vl.Unlock()
+
+		l.mu.Lock()
+		vl.refs--
+		if vl.refs == 0 {
+			delete(l.locks, name)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,202 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// blankVolumeAlignment is the size blank zvols are rounded up to, a multiple
+// of any volblocksize.
+const blankVolumeAlignment = 1 << 20
+
+// ZFSStorage keeps VM disks as zvols cloned from snapshots of the source image
+// zvols, so creating a disk doesn't copy any data.
+type ZFSStorage struct {
+	dataset       string
+	imagesDataset string
+	zfs           string
+	runner        CommandRunner
+}
+
+// NewZFSStorage creates a storage cloning images from imagesDataset into
+// dataset. zfs is the zfs binary to run.
+func NewZFSStorage(dataset string, imagesDataset string, zfs string, runner CommandRunner) (StorageManager, error) {
+	s := ZFSStorage{dataset: dataset, imagesDataset: imagesDataset, zfs: zfs, runner: runner}
+
+	for _, ds := range []string{dataset, imagesDataset} {
+		if _, err := s.get(context.Background(), ds, "type"); err != nil {
+			return nil, fmt.Errorf("dataset %s not available: %v", ds, err)
+		}
+	}
+	return s, nil
+}
+
+func (s ZFSStorage) run(ctx context.Context, args ...string) (string, error) {
+	out, err := s.runner.Run(ctx, s.zfs, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// get returns the parsable value of a dataset property.
+func (s ZFSStorage) get(ctx context.Context, dataset string, property string) (string, error) {
+	return s.run(ctx, "get", "-H", "-p", "-o", "value", property, dataset)
+}
+
+func (s ZFSStorage) getUint(ctx context.Context, dataset string, property string) (uint64, error) {
+	v, err := s.get(ctx, dataset, property)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected %s of %s: %q", property, dataset, v)
+	}
+	return n, nil
+}
+
+func (s ZFSStorage) vmDataset(name string) string {
+	return path.Join(s.dataset, name)
+}
+
+// sourceSnapshot returns the snapshot to clone a source image from. Images
+// can be given as image@snapshot, otherwise the latest snapshot of the image
+// is used.
+func (s ZFSStorage) sourceSnapshot(ctx context.Context, sourceImage string) (string, error) {
+	if sourceImage == "" || strings.HasPrefix(sourceImage, "/") || strings.Contains(sourceImage, "..") {
+		return "", fmt.Errorf("invalid source image %q", sourceImage)
+	}
+
+	image := path.Join(s.imagesDataset, sourceImage)
+	if strings.Contains(sourceImage, "@") {
+		return image, nil
+	}
+
+	out, err := s.run(ctx, "list", "-H", "-o", "name", "-t", "snapshot", "-s", "creation", "-d", "1", image)
+	if err != nil {
+		return "", err
+	}
+	snapshots := strings.Fields(out)
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("source image %s has no snapshots", sourceImage)
+	}
+	return snapshots[len(snapshots)-1], nil
+}
+
+func (s ZFSStorage) CreateStorage(ctx context.Context, name string, size uint64, sourceImage string) error {
+	if sourceImage == "" {
+		size = (size + blankVolumeAlignment - 1) / blankVolumeAlignment * blankVolumeAlignment
+		_, err := s.run(ctx, "create", "-V", strconv.FormatUint(size, 10), "-o", "vmregistry:vm="+name, s.vmDataset(name))
+		return err
+	}
+
+	snapshot, err := s.sourceSnapshot(ctx, sourceImage)
+	if err != nil {
+		return err
+	}
+
+	t, err := s.get(ctx, snapshot, "type")
+	if err != nil {
+		return err
+	}
+	if t != "snapshot" {
+		return fmt.Errorf("source image %s is a %s, not a snapshot", snapshot, t)
+	}
+
+	volsize, err := s.getUint(ctx, snapshot, "volsize")
+	if err != nil {
+		return err
+	}
+	if size < volsize {
+		return fmt.Errorf("size %d is smaller than the %d bytes of source image %s", size, volsize, sourceImage)
+	}
+
+	dataset := s.vmDataset(name)
+	_, err = s.run(ctx, "clone", "-o", "vmregistry:vm="+name, snapshot, dataset)
+	if err != nil {
+		return err
+	}
+
+	if size > volsize {
+		err = s.setVolsize(ctx, dataset, size)
+		if err != nil {
+			s.run(ctx, "destroy", dataset)
+			return err
+		}
+	}
+	return nil
+}
+
+// setVolsize changes the zvol size, rounded up to its block size.
+func (s ZFSStorage) setVolsize(ctx context.Context, dataset string, size uint64) error {
+	blocksize, err := s.getUint(ctx, dataset, "volblocksize")
+	if err != nil {
+		return err
+	}
+	if blocksize > 0 {
+		size = (size + blocksize - 1) / blocksize * blocksize
+	}
+	_, err = s.run(ctx, "set", "volsize="+strconv.FormatUint(size, 10), dataset)
+	return err
+}
+
+func (s ZFSStorage) ResizeStorage(ctx context.Context, name string, size uint64) error {
+	return s.setVolsize(ctx, s.vmDataset(name), size)
+}
+
+// RemoveStorage destroys the clone along with its snapshots.
+func (s ZFSStorage) RemoveStorage(ctx context.Context, name string) error {
+	_, err := s.run(ctx, "destroy", "-r", s.vmDataset(name))
+	if err != nil && strings.Contains(err.Error(), "does not exist") {
+		return nil
+	}
+	return err
+}
+
+func (s ZFSStorage) CreateSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "snapshot", s.vmDataset(name)+"@"+snapshot)
+	return err
+}
+
+// RevertSnapshot rolls the zvol back. It fails if there are later snapshots,
+// rather than destroying them.
+func (s ZFSStorage) RevertSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "rollback", s.vmDataset(name)+"@"+snapshot)
+	return err
+}
+
+func (s ZFSStorage) RemoveSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.run(ctx, "destroy", s.vmDataset(name)+"@"+snapshot)
+	if err != nil && strings.Contains(err.Error(), "could not find any snapshots") {
+		return nil
+	}
+	return err
+}
+
+func (s ZFSStorage) StorageBlockDevice(name string) string {
+	return path.Join("/dev/zvol", s.vmDataset(name))
+}
+
+func (s ZFSStorage) StorageFormat() string {
+	return "raw"
+}
@@ -0,0 +1,216 @@
+/*
+
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+*/
+
+package server
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// fakeRunner records the commands it's asked to run and answers them from
+// canned outputs and errors, keyed by the command line.
+type fakeRunner struct {
+	outputs map[string]string
+	errors  map[string]error
+	calls   []string
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{outputs: map[string]string{}, errors: map[string]error{}}
+}
+
+func (r *fakeRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	r.calls = append(r.calls, cmd)
+	if err, ok := r.errors[cmd]; ok {
+		return nil, err
+	}
+	return []byte(r.outputs[cmd]), nil
+}
+
+// newTestZFSStorage creates a storage on pool/vms and pool/images, and forgets
+// the commands run to check the datasets.
+func newTestZFSStorage(t *testing.T, r *fakeRunner) StorageManager {
+	r.outputs["zfs get -H -p -o value type pool/vms"] = "filesystem\n"
+	r.outputs["zfs get -H -p -o value type pool/images"] = "filesystem\n"
+
+	s, err := NewZFSStorage("pool/vms", "pool/images", "zfs", r)
+	if err != nil {
+		t.Fatalf("NewZFSStorage failed: %v", err)
+	}
+	r.calls = nil
+	return s
+}
+
+func checkCalls(t *testing.T, r *fakeRunner, want ...string) {
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("unexpected commands\ngot:  %q\nwant: %q", r.calls, want)
+	}
+}
+
+func TestZFSCreateStorageClonesLatestSnapshot(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.outputs["zfs list -H -o name -t snapshot -s creation -d 1 pool/images/ubuntu"] = "pool/images/ubuntu@v1\npool/images/ubuntu@v2\n"
+	r.outputs["zfs get -H -p -o value type pool/images/ubuntu@v2"] = "snapshot\n"
+	r.outputs["zfs get -H -p -o value volsize pool/images/ubuntu@v2"] = "10737418240\n"
+	r.outputs["zfs get -H -p -o value volblocksize pool/vms/vm1"] = "16384\n"
+
+	if err := s.CreateStorage(context.Background(), "vm1", 10737418240+1, "ubuntu"); err != nil {
+		t.Fatalf("CreateStorage failed: %v", err)
+	}
+	checkCalls(t, r,
+		"zfs list -H -o name -t snapshot -s creation -d 1 pool/images/ubuntu",
+		"zfs get -H -p -o value type pool/images/ubuntu@v2",
+		"zfs get -H -p -o value volsize pool/images/ubuntu@v2",
+		"zfs clone -o vmregistry:vm=vm1 pool/images/ubuntu@v2 pool/vms/vm1",
+		"zfs get -H -p -o value volblocksize pool/vms/vm1",
+		"zfs set volsize=10737434624 pool/vms/vm1",
+	)
+}
+
+func TestZFSCreateStorageSameSizeSkipsVolsize(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.outputs["zfs get -H -p -o value type pool/images/ubuntu@v1"] = "snapshot\n"
+	r.outputs["zfs get -H -p -o value volsize pool/images/ubuntu@v1"] = "1048576\n"
+
+	if err := s.CreateStorage(context.Background(), "vm1", 1048576, "ubuntu@v1"); err != nil {
+		t.Fatalf("CreateStorage failed: %v", err)
+	}
+	checkCalls(t, r,
+		"zfs get -H -p -o value type pool/images/ubuntu@v1",
+		"zfs get -H -p -o value volsize pool/images/ubuntu@v1",
+		"zfs clone -o vmregistry:vm=vm1 pool/images/ubuntu@v1 pool/vms/vm1",
+	)
+}
+
+func TestZFSCreateStorageRejectsSmallerSize(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.outputs["zfs get -H -p -o value type pool/images/ubuntu@v1"] = "snapshot\n"
+	r.outputs["zfs get -H -p -o value volsize pool/images/ubuntu@v1"] = "1048576\n"
+
+	if err := s.CreateStorage(context.Background(), "vm1", 1024, "ubuntu@v1"); err == nil {
+		t.Fatalf("CreateStorage succeeded with a size smaller than the image")
+	}
+	for _, call := range r.calls {
+		if strings.HasPrefix(call, "zfs clone") {
+			t.Errorf("unexpected clone: %s", call)
+		}
+	}
+}
+
+func TestZFSRemoveStorage(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	if err := s.RemoveStorage(context.Background(), "vm1"); err != nil {
+		t.Fatalf("RemoveStorage failed: %v", err)
+	}
+	checkCalls(t, r, "zfs destroy -r pool/vms/vm1")
+}
+
+func TestZFSRemoveStorageMissing(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.errors["zfs destroy -r pool/vms/vm1"] = errors.New("cannot open 'pool/vms/vm1': dataset does not exist")
+	if err := s.RemoveStorage(context.Background(), "vm1"); err != nil {
+		t.Errorf("RemoveStorage of a missing dataset failed: %v", err)
+	}
+
+	r.errors["zfs destroy -r pool/vms/vm2"] = errors.New("cannot destroy 'pool/vms/vm2': dataset is busy")
+	if err := s.RemoveStorage(context.Background(), "vm2"); err == nil {
+		t.Errorf("RemoveStorage of a busy dataset succeeded")
+	}
+}
+
+func TestZFSResizeStorageRoundsToBlockSize(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.outputs["zfs get -H -p -o value volblocksize pool/vms/vm1"] = "8192\n"
+
+	if err := s.ResizeStorage(context.Background(), "vm1", 8193); err != nil {
+		t.Fatalf("ResizeStorage failed: %v", err)
+	}
+	checkCalls(t, r,
+		"zfs get -H -p -o value volblocksize pool/vms/vm1",
+		"zfs set volsize=16384 pool/vms/vm1",
+	)
+}
+
+func TestZFSCreateStorageBlank(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	if err := s.CreateStorage(context.Background(), "vm1_data", 1048576+1, ""); err != nil {
+		t.Fatalf("CreateStorage failed: %v", err)
+	}
+	checkCalls(t, r, "zfs create -V 2097152 -o vmregistry:vm=vm1_data pool/vms/vm1_data")
+}
+
+func TestZFSSnapshots(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+	ctx := context.Background()
+
+	if err := s.CreateSnapshot(ctx, "vm1", "before"); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if err := s.RevertSnapshot(ctx, "vm1", "before"); err != nil {
+		t.Fatalf("RevertSnapshot failed: %v", err)
+	}
+	if err := s.RemoveSnapshot(ctx, "vm1", "before"); err != nil {
+		t.Fatalf("RemoveSnapshot failed: %v", err)
+	}
+	checkCalls(t, r,
+		"zfs snapshot pool/vms/vm1@before",
+		"zfs rollback pool/vms/vm1@before",
+		"zfs destroy pool/vms/vm1@before",
+	)
+}
+
+func TestZFSRemoveSnapshotMissing(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.errors["zfs destroy pool/vms/vm1@gone"] = errors.New("could not find any snapshots to destroy; check snapshot names.")
+	if err := s.RemoveSnapshot(context.Background(), "vm1", "gone"); err != nil {
+		t.Errorf("RemoveSnapshot of a missing snapshot failed: %v", err)
+	}
+}
+
+func TestZFSRevertSnapshotKeepsLaterSnapshots(t *testing.T) {
+	r := newFakeRunner()
+	s := newTestZFSStorage(t, r)
+
+	r.errors["zfs rollback pool/vms/vm1@old"] = errors.New("cannot rollback to 'pool/vms/vm1@old': more recent snapshots or bookmarks exist")
+	if err := s.RevertSnapshot(context.Background(), "vm1", "old"); err == nil {
+		t.Errorf("RevertSnapshot succeeded despite later snapshots")
+	}
+}
@@ -71,12 +71,14 @@ const pageTemplateText = `
 			<th>Name</th>
 			<th>IP</th>
 			<th>MAC</th>
+			<th>State</th>
 		</tr>
 		{{range .Vms}}
 		<tr>
 			<td>{{.Name}}</td>
 			<td>{{.Ip}}</td>
 			<td>{{.Mac}}</td>
+			<td>{{.State}}</td>
 		</tr>
 		{{end}}
 	</table>